@@ -0,0 +1,143 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/tIsGoud/makeSnapshot/pkg/vra"
+
+	"github.com/spf13/viper"
+)
+
+// configuredAction returns the action with the binding and the fallback names
+// of the config file, the key holds "binding" and "names". An empty binding
+// or name list keeps the default.
+func configuredAction(action vra.ActionSelector, key string) vra.ActionSelector {
+	if binding := viper.GetString(key + ".binding"); binding != "" {
+		action.BindingID = binding
+	}
+	if names := viper.GetStringSlice(key + ".names"); len(names) > 0 {
+		action.Names = names
+	}
+	return action
+}
+
+// runResourceAction runs the steps 3 to 6 of a day-2 action without input,
+// like reverting to the snapshot. The request template is sent back as
+// provided by vRA. The action is named in the tracing, e.g. "revert".
+func runResourceAction(ctx context.Context, client *vra.Client, target machineTarget, action string, selector vra.ActionSelector) error {
+	// Step 3 - Get resource action id (GET {baseURL}/catalog-service/api/consumer/resources/{vmID}/actions/)
+	traceInfo("Step 3 - Get " + action + " resource action ID for " + target.name)
+	actionID, err := client.GetResourceActionID(ctx, target.id, selector)
+	if err != nil {
+		return err
+	}
+
+	// Step 4 - Get resource action template (GET {baseURL}/catalog-service/api/consumer/resources/{vmID}/actions/{actionID}/requests/template)
+	traceInfo("Step 4 - Get resource action template for " + target.name)
+	template, err := client.GetResourceActionRequestTemplate(ctx, target.id, actionID)
+	if err != nil {
+		return err
+	}
+	template.Description = "makeSnapshot call"
+	if _, ok := template.Data[tenantRefField]; ok {
+		if err := template.SetData(tenantRefField, viper.GetString("tenant")); err != nil {
+			return err
+		}
+	}
+
+	// On dry-run skip the request
+	if dryRun {
+		traceInfo("Step 5 - Skipped because of dry-run for " + target.name)
+		traceInfo("Step 6 - Skipped because of dry-run for " + target.name)
+		return nil
+	}
+
+	// Step 5 - Send resource action request (POST {baseURL}/catalog-service/api/consumer/resources/{vmID}/actions/{actionID}/requests/)
+	traceInfo("Step 5 - Send " + action + " request for " + target.name)
+	requestStatusURL, err := client.SendResourceActionRequest(ctx, target.id, actionID, template)
+	if err != nil {
+		return err
+	}
+
+	// Step 6 - Get request result state (GET {baseURL}/catalog-service/api/consumer/{requestStatusURL})
+	return waitForRequest(ctx, client, action, target.name, requestStatusURL)
+}
+
+// tenantRefField is the template field with the tenant of the request
+const tenantRefField = "provider-__asd_tenantRef"
+
+// waitForRequest polls the request of the action until it is finished, the
+// action is named in the tracing and errors, e.g. "snapshot"
+func waitForRequest(ctx context.Context, client *vra.Client, action, machine, requestStatusURL string) error {
+	traceInfo("Step 6 - Get " + action + " request status for " + machine + "...")
+
+	pollOptions := vra.PollOptions{
+		Interval:        viper.GetDuration("pollInterval"),
+		MaxInterval:     viper.GetDuration("pollMaxInterval"),
+		Multiplier:      viper.GetFloat64("pollBackoff"),
+		Jitter:          viper.GetFloat64("pollJitter"),
+		MaxWait:         viper.GetDuration("maxWait"),
+		ApprovalTimeout: viper.GetDuration("approvalTimeout"),
+	}
+
+	err := client.WaitForRequest(ctx, requestStatusURL, pollOptions, func(request *vra.ResourceActionRequest) {
+		traceInfo("Step 6 - " + strings.ToUpper(action[:1]) + action[1:] + " request status for " + machine + ": " + request.StateName)
+	})
+	if err != nil && viper.GetBool("cancelOnAbort") && (errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled)) {
+		err = cancelRequest(client, action, machine, requestStatusURL, err)
+	}
+
+	var failed *vra.RequestFailedError
+	switch {
+	case errors.As(err, &failed):
+		return fmt.Errorf("%s request: %w", action, err)
+	case errors.Is(err, vra.ErrApprovalTimeout):
+		return fmt.Errorf("%s request not approved within %s: %w", action, pollOptions.ApprovalTimeout, err)
+	case errors.Is(err, vra.ErrRequestTimeout):
+		return fmt.Errorf("%s request not finished within %s: %w", action, pollOptions.MaxWait, err)
+	case errors.Is(err, context.Canceled):
+		return fmt.Errorf("%s request interrupted: %w", action, err)
+	}
+	return err
+}
+
+// cancelRequest cancels the request that is no longer waited for, after a
+// timeout or an interrupt. The result of the cancellation is added to the error.
+func cancelRequest(client *vra.Client, action, machine, requestStatusURL string, err error) error {
+	// The context of the run may already be cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	traceInfo("Step 6 - Cancel " + action + " request for " + machine)
+	if cancelErr := client.CancelRequest(ctx, requestStatusURL); cancelErr != nil {
+		log.Printf("Error: Unable to cancel %s request for %s: %s", action, machine, cancelErr)
+		return fmt.Errorf("%w (unable to cancel the request: %s)", err, cancelErr)
+	}
+	log.Printf("Cancelled %s request for %s", action, machine)
+	return fmt.Errorf("%w (request cancelled)", err)
+}
@@ -0,0 +1,125 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/tIsGoud/makeSnapshot/pkg/vra"
+
+	"github.com/spf13/cobra"
+)
+
+// deleteCmd represents the delete command
+var deleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete the snapshot of a virtual machine",
+	Long: `
+The delete option deletes the existing snapshot of a virtual machine with the
+"Delete Snapshot" day-2 action.
+
+Only one snapshot per VM is allowed, a stale snapshot blocks the next snapshot
+with '--keepExisting' and uses datastore space. The snapshot of every machine
+is shown and has to be confirmed, unless '--yes' is used. A machine without a
+snapshot fails with its own exit status code.`,
+	Example: `  Delete the snapshot of a virtual machine, with tracing information:
+  makeSnapshot delete -m myVirtualMachine -t
+
+  Delete the snapshots of several machines without confirmation:
+  makeSnapshot delete -m web01 -m web02 --yes
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runOnMachines(cmd, machineCommand{
+			progress: "Deleting snapshot of",
+			action:   "Delete the snapshot of",
+			confirm:  confirmSnapshots,
+			run:      deleteMachine,
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(deleteCmd)
+
+	addMachineFlags(deleteCmd)
+	addRequestFlags(deleteCmd)
+	addResultOutputFlag(deleteCmd)
+}
+
+// confirmSnapshots reads the current snapshots of the machines and asks for
+// confirmation per machine, unless --yes is used. A machine that is not
+// confirmed is skipped, when no machine is confirmed the run is aborted.
+func confirmSnapshots(ctx context.Context, client *vra.Client, targets []machineTarget) ([]machineTarget, error) {
+	var confirmed []machineTarget
+	asked, declined := 0, 0
+	for _, target := range targets {
+		if target.err != nil {
+			confirmed = append(confirmed, target)
+			continue
+		}
+
+		traceInfo("Step 2 - Get snapshots of " + target.name)
+		resource, err := client.GetCatalogResource(ctx, target.id)
+		if err != nil {
+			target.err = err
+			confirmed = append(confirmed, target)
+			continue
+		}
+		target.resource = *resource
+
+		snapshots := resource.Snapshots()
+		if len(snapshots) == 0 || assumeYes || dryRun {
+			confirmed = append(confirmed, target)
+			continue
+		}
+
+		asked++
+		ok, err := confirm(ctx, fmt.Sprintf("Delete snapshot %s of %s?", describeSnapshots(snapshots), target.name))
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			log.Printf("%s: Skipped, not confirmed", target.name)
+			declined++
+			continue
+		}
+		confirmed = append(confirmed, target)
+	}
+
+	if asked > 0 && asked == declined {
+		return nil, errNotConfirmed
+	}
+	return confirmed, nil
+}
+
+// deleteMachine runs the steps 3 to 6 of the snapshot deletion for a single machine
+func deleteMachine(ctx context.Context, client *vra.Client, target machineTarget) error {
+	snapshots := target.resource.Snapshots()
+	if len(snapshots) == 0 {
+		return &vra.NoSnapshotError{Machine: target.name}
+	}
+	traceInfo("Step 3 - Deleting snapshot " + describeSnapshots(snapshots) + " of " + target.name)
+
+	return runResourceAction(ctx, client, target, "delete snapshot", configuredAction(vra.DeleteSnapshotAction, "deleteAction"))
+}
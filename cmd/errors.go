@@ -0,0 +1,125 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/tIsGoud/makeSnapshot/pkg/vra"
+)
+
+// Exit status codes, every class of failure has its own stable code.
+// Do not renumber, scripts and pipelines depend on them.
+const (
+	exitOK               = 0
+	exitError            = 1
+	exitConfigInvalid    = 2
+	exitAuthFailed       = 3
+	exitMachineNotFound  = 4
+	exitSnapshotExists   = 5
+	exitRequestFailed    = 6
+	exitTimeout          = 7
+	exitMultipleFailures = 8
+	exitAborted          = 9
+	exitAmbiguousMachine = 10
+	exitNoSnapshot       = 11
+	exitRequestRejected  = 12
+	exitRequestCancelled = 13
+	exitInterrupted      = 130
+)
+
+// exitCodeTable documents the exit status codes, printed by the exitCodes command
+var exitCodeTable = []struct {
+	code        int
+	name        string
+	description string
+}{
+	{exitOK, "OK", "the snapshot is created (or the dry-run succeeded)"},
+	{exitError, "Error", "unclassified error, e.g. a connection problem or an invalid flag"},
+	{exitConfigInvalid, "ConfigInvalid", "the config file is missing or invalid, or an option is not supported"},
+	{exitAuthFailed, "AuthFailed", "unable to log in, or the bearer token is not accepted"},
+	{exitMachineNotFound, "MachineNotFound", "the virtual machine is not found in the vRA catalog"},
+	{exitSnapshotExists, "SnapshotExists", "a snapshot already exists and --keepExisting is set"},
+	{exitRequestFailed, "RequestFailed", "vRA reports the snapshot request as failed"},
+	{exitTimeout, "Timeout", "the request did not finish within --max-wait or was not approved within --approval-timeout"},
+	{exitMultipleFailures, "MultipleFailures", "several machines failed for different reasons, see the result per machine"},
+	{exitAborted, "Aborted", "the selected machines were not confirmed, see --yes"},
+	{exitAmbiguousMachine, "AmbiguousMachine", "the machine name matches more than one virtual machine"},
+	{exitNoSnapshot, "NoSnapshot", "the virtual machine has no snapshot to delete"},
+	{exitRequestRejected, "RequestRejected", "the approver rejected the request"},
+	{exitRequestCancelled, "RequestCancelled", "the request was cancelled in vRA"},
+	{exitInterrupted, "Interrupted", "the application received SIGINT or SIGTERM"},
+}
+
+// classError assigns an exit status code to an error that can not be
+// classified by its type, like an invalid configuration.
+type classError struct {
+	code int
+	err  error
+}
+
+func (e *classError) Error() string {
+	return e.err.Error()
+}
+
+func (e *classError) Unwrap() error {
+	return e.err
+}
+
+// newConfigError returns an error for an invalid configuration
+func newConfigError(format string, v ...interface{}) error {
+	return &classError{code: exitConfigInvalid, err: fmt.Errorf(format, v...)}
+}
+
+// exitCode returns the exit status code for the error
+func exitCode(err error) int {
+	var ce *classError
+	var me *machinesError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &me):
+		return me.exitCode()
+	case errors.As(err, &ce):
+		return ce.code
+	case errors.Is(err, context.Canceled):
+		return exitInterrupted
+	case errors.Is(err, context.DeadlineExceeded):
+		return exitTimeout
+	case errors.Is(err, vra.ErrUnauthorized):
+		return exitAuthFailed
+	case errors.Is(err, vra.ErrMachineNotFound):
+		return exitMachineNotFound
+	case errors.Is(err, vra.ErrAmbiguousMachine):
+		return exitAmbiguousMachine
+	case errors.Is(err, vra.ErrNoSnapshot):
+		return exitNoSnapshot
+	case errors.Is(err, vra.ErrRequestRejected):
+		return exitRequestRejected
+	case errors.Is(err, vra.ErrRequestCancelled):
+		return exitRequestCancelled
+	case errors.Is(err, vra.ErrRequestFailed):
+		return exitRequestFailed
+	}
+	return exitError
+}
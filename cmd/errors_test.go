@@ -0,0 +1,94 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/tIsGoud/makeSnapshot/pkg/vra"
+)
+
+// failedRequest returns the error of a request that ended in the state
+func failedRequest(state string) error {
+	return &vra.RequestFailedError{Request: &vra.ResourceActionRequest{State: state}}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"nil", nil, exitOK},
+		{"config", newConfigError("no password"), exitConfigInvalid},
+		{"wrapped config", fmt.Errorf("step 1: %w", newConfigError("no password")), exitConfigInvalid},
+		{"class wins over the wrapped error", &classError{code: exitAuthFailed, err: context.Canceled}, exitAuthFailed},
+		{"interrupted", fmt.Errorf("snapshot: %w", context.Canceled), exitInterrupted},
+		{"deadline", context.DeadlineExceeded, exitTimeout},
+		{"request timeout", vra.ErrRequestTimeout, exitTimeout},
+		{"approval timeout", fmt.Errorf("snapshot: %w", vra.ErrApprovalTimeout), exitTimeout},
+		{"unauthorized", fmt.Errorf("login: %w", vra.ErrUnauthorized), exitAuthFailed},
+		{"machine not found", fmt.Errorf("web01: %w", vra.ErrMachineNotFound), exitMachineNotFound},
+		{"ambiguous machine", &vra.AmbiguousMachineError{Machine: "web01"}, exitAmbiguousMachine},
+		{"no snapshot", fmt.Errorf("web01: %w", vra.ErrNoSnapshot), exitNoSnapshot},
+		{"request failed", failedRequest(vra.StateFailed), exitRequestFailed},
+		{"request rejected", failedRequest(vra.StateRejected), exitRequestRejected},
+		{"request pre-rejected", failedRequest(vra.StatePreRejected), exitRequestRejected},
+		{"request cancelled", failedRequest(vra.StateCancelled), exitRequestCancelled},
+		{"other", errors.New("connection refused"), exitError},
+	}
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("%s: exitCode(%v) = %d, want %d", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestMachinesErrorExitCode(t *testing.T) {
+	notFound := fmt.Errorf("web01: %w", vra.ErrMachineNotFound)
+	failed := &vra.RequestFailedError{}
+
+	tests := []struct {
+		name   string
+		failed []error
+		want   int
+	}{
+		{"single", []error{notFound}, exitMachineNotFound},
+		{"same class", []error{notFound, fmt.Errorf("web02: %w", vra.ErrMachineNotFound)}, exitMachineNotFound},
+		{"mixed", []error{notFound, failed}, exitMultipleFailures},
+		{"mixed with an unclassified error", []error{failed, errors.New("connection reset")}, exitMultipleFailures},
+	}
+	for _, tt := range tests {
+		err := &machinesError{total: 3}
+		for i, failure := range tt.failed {
+			err.failed = append(err.failed, machineResult{machine: fmt.Sprintf("web%02d", i+1), err: failure})
+		}
+		if got := exitCode(err); got != tt.want {
+			t.Errorf("%s: exitCode = %d, want %d", tt.name, got, tt.want)
+		}
+		if got := exitCode(fmt.Errorf("snapshot: %w", err)); got != tt.want {
+			t.Errorf("%s: exitCode of the wrapped error = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
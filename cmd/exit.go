@@ -0,0 +1,71 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// cleanups are run in reverse order before the application exits, also on a failure
+var cleanups []func()
+
+// atExit registers a function that is run before the application exits
+func atExit(cleanup func()) {
+	cleanups = append(cleanups, cleanup)
+}
+
+// runCleanups runs the registered cleanup functions once
+func runCleanups() {
+	for i := len(cleanups) - 1; i >= 0; i-- {
+		cleanups[i]()
+	}
+	cleanups = nil
+}
+
+// exit runs the cleanup functions and exits with the status code
+func exit(code int) {
+	runCleanups()
+	os.Exit(code)
+}
+
+// signalContext returns a context that is cancelled on SIGINT or SIGTERM,
+// all API calls stop and the application exits through the cleanup functions.
+// A second SIGINT or SIGTERM has the default behaviour.
+func signalContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Printf("Received %s, stopping", sig)
+		cancel()
+
+		// A second signal stops the application immediately
+		signal.Stop(signals)
+	}()
+
+	return ctx
+}
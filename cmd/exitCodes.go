@@ -20,35 +20,40 @@
 
 package cmd
 
-// GetBearerTokenRequest ...
-type GetBearerTokenRequest struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
-	Tenant   string `json:"tenant"`
-}
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// exitCodesCmd represents the exitCodes command
+var exitCodesCmd = &cobra.Command{
+	Use:   "exitCodes",
+	Short: "Print the exit status codes",
+	Long: `
+The exitCodes option prints the exit status codes of the application.
 
-// GetBearerTokenResponse ...
-type GetBearerTokenResponse struct {
-	Expires string `json:"expires"`
-	ID      string `json:"id"`
-	Tenant  string `json:"tenant"`
+Every class of failure has its own exit status code, a workflow can use the
+code to decide on the next step. The codes are stable between releases.`,
+	Example: `  Print the exit status codes:
+  makeSnapshot exitCodes
+`,
+	Run: func(cmd *cobra.Command, args []string) {
+		printExitCodes()
+	},
 }
 
-// SnapShotTemplate ...
-type SnapShotTemplate struct {
-	Type        string      `json:"type"`
-	ResourceID  string      `json:"resourceId"`
-	ActionID    string      `json:"actionId"`
-	Description interface{} `json:"description"`
-	Data        Data        `json:"data"`
+func init() {
+	rootCmd.AddCommand(exitCodesCmd)
 }
 
-// Data ...
-type Data struct {
-	ProviderASDPRESENTATIONINSTANCE interface{} `json:"provider-__ASD_PRESENTATION_INSTANCE"`
-	ProviderAsdTenantRef            string      `json:"provider-__asd_tenantRef"`
-	ProviderDeleteExisting          interface{} `json:"provider-deleteExisting"`
-	ProviderDescription             interface{} `json:"provider-description"`
-	ProviderExistingSnapshotName    interface{} `json:"provider-existingSnapshotName"`
-	ProviderName                    interface{} `json:"provider-name"`
+func printExitCodes() {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CODE\tNAME\tDESCRIPTION")
+	for _, exitCode := range exitCodeTable {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", exitCode.code, exitCode.name, exitCode.description)
+	}
+	w.Flush()
 }
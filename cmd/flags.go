@@ -0,0 +1,88 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"github.com/tIsGoud/makeSnapshot/pkg/vra"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// The flags that are used by more than one command are defined once, every
+// command adds the same flags. The viper bindings of a flag then hold for
+// each command and a command only shows the flags it uses.
+var (
+	machineFlags = newMachineFlags()
+	requestFlags = newRequestFlags()
+	loginFlags   = newLoginFlags()
+)
+
+// newMachineFlags returns the flags to select the machines
+func newMachineFlags() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("machines", pflag.ContinueOnError)
+	flags.StringArrayVarP(&machineNames, "machineName", "m", nil, "name of the virtual machine, default case sensitive (repeat for more machines)")
+	flags.StringVar(&machinesFile, "machines-file", "", "file with the names of the virtual machines, one per line, '-' reads from stdin")
+	flags.String("match", string(vra.MatchExact), "how the 'machineName' is matched: exact, glob or regex (overrides the match value in the config file)")
+	flags.BoolVarP(&ignoreCase, "ignoreCase", "i", false, "do a case-insensitive search for the 'machineName'")
+	flags.StringArrayVar(&resourceIDs, "resource-id", nil, "catalog resource id of the virtual machine (repeat for more machines)")
+	flags.StringArrayVar(&ipAddresses, "ip", nil, "IP address of the virtual machine (repeat for more machines)")
+	flags.StringArrayVar(&hostnames, "hostname", nil, "host name or FQDN of the virtual machine (repeat for more machines)")
+	flags.StringArrayVar(&properties, "property", nil, "select the virtual machines with the custom property key=value (repeat for more properties)")
+	flags.StringSlice("platform", nil, "only look up machines on these platforms, e.g. vSphere,HyperV (overrides the platform value in the config file)")
+	flags.Bool("include-cloud", false, "also look up cloud machines (overrides the includeCloud value in the config file)")
+	return flags
+}
+
+// newRequestFlags returns the flags to confirm, send and follow up the requests
+func newRequestFlags() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("requests", pflag.ContinueOnError)
+	flags.BoolVarP(&dryRun, "dry-run", "r", false, "dry-run the application, running full initialization and pre-request calls only")
+	flags.BoolVarP(&assumeYes, "yes", "y", false, "do not ask for confirmation when more than one machine is selected")
+	flags.Int("parallel", 4, "maximum number of machines to handle at the same time (overrides the parallel value in the config file)")
+	flags.Duration("poll-interval", vra.DefaultPollOptions.Interval, "time between two request status checks (overrides the pollInterval value in the config file)")
+	flags.Float64("poll-backoff", vra.DefaultPollOptions.Multiplier, "multiply the poll interval by this factor after every check (overrides the pollBackoff value in the config file)")
+	flags.Duration("poll-max-interval", vra.DefaultPollOptions.MaxInterval, "maximum time between two request status checks (overrides the pollMaxInterval value in the config file)")
+	flags.Float64("poll-jitter", 0.1, "randomize every poll interval by this fraction, e.g. 0.1 is +/- 10% (overrides the pollJitter value in the config file)")
+	flags.Duration("max-wait", 0, "maximum time to wait for the request, not counting the approval, 0 is no limit (overrides the maxWait value in the config file)")
+	flags.Duration("approval-timeout", 0, "maximum time to wait for the approval of the request, 0 is no limit (overrides the approvalTimeout value in the config file)")
+	flags.Bool("cancel-on-abort", false, "cancel the vRA request on a timeout or an interrupt (overrides the cancelOnAbort value in the config file)")
+	return flags
+}
+
+// newLoginFlags returns the flags of the bearer token
+func newLoginFlags() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("login", pflag.ContinueOnError)
+	flags.Bool("revoke-token", false, "revoke the bearer token when the application exits (overrides the revokeToken value in the config file)")
+	flags.Bool("token-cache", false, "reuse the bearer token of a previous run until it expires (overrides the tokenCache value in the config file)")
+	return flags
+}
+
+// addMachineFlags adds the flags to select the machines and to log in to the command
+func addMachineFlags(cmd *cobra.Command) {
+	cmd.Flags().AddFlagSet(machineFlags)
+	cmd.Flags().AddFlagSet(loginFlags)
+}
+
+// addRequestFlags adds the flags of the requests to the command
+func addRequestFlags(cmd *cobra.Command) {
+	cmd.Flags().AddFlagSet(requestFlags)
+}
@@ -0,0 +1,57 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestCommandFlags(t *testing.T) {
+	tests := []struct {
+		cmd     *cobra.Command
+		flags   []string
+		missing []string
+	}{
+		{rootCmd, []string{"config", "trace", "machineName", "token-cache", "dry-run", "poll-interval", "poll-jitter", "poll-max-interval", "keepExisting"}, nil},
+		{revertCmd, []string{"config", "machineName", "platform", "revoke-token", "yes", "max-wait", "output"}, []string{"keepExisting", "name"}},
+		{deleteCmd, []string{"domain", "hostname", "token-cache", "parallel", "cancel-on-abort"}, []string{"keepExisting", "quiesce"}},
+		{listCmd, []string{"config", "machineName", "match", "include-cloud", "token-cache"}, []string{"dry-run", "yes", "parallel", "poll-interval", "poll-jitter", "poll-max-interval", "max-wait"}},
+		{generateConfigCmd, []string{"config", "trace"}, []string{"machineName", "dry-run", "token-cache", "poll-interval"}},
+		{exitCodesCmd, []string{"domain"}, []string{"machineName", "parallel", "revoke-token"}},
+	}
+	for _, tt := range tests {
+		lookup := func(name string) bool {
+			return tt.cmd.Flags().Lookup(name) != nil || tt.cmd.PersistentFlags().Lookup(name) != nil || tt.cmd.InheritedFlags().Lookup(name) != nil
+		}
+		for _, name := range tt.flags {
+			if !lookup(name) {
+				t.Errorf("%s has no --%s flag", tt.cmd.Name(), name)
+			}
+		}
+		for _, name := range tt.missing {
+			if lookup(name) {
+				t.Errorf("%s has a --%s flag", tt.cmd.Name(), name)
+			}
+		}
+	}
+}
@@ -67,6 +67,8 @@ func writeSampleConfigFile(configFile string) {
 		file.WriteString("domain: \"your domain name\"\n")
 		file.WriteString("username: \"your username without domain\"\n")
 		file.WriteString("password: \"your password\"\n")
+		file.WriteString("snapshotName: '" + defaultSnapshotName + "'\n")
+		file.WriteString("snapshotDescription: '" + defaultSnapshotDescription + "'\n")
 		file.WriteString("...\n")
 		file.Sync()
 		file.Close()
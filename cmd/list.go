@@ -0,0 +1,169 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/tIsGoud/makeSnapshot/pkg/vra"
+
+	"github.com/spf13/cobra"
+)
+
+var listOutput string
+
+// listCmd represents the list command
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the snapshots of virtual machines",
+	Long: `
+The list option shows the snapshots of the selected virtual machines with their
+name, description, creation time and age. The snapshots are read from the
+resource data of the machines in vRA.
+
+The output is a table, JSON or CSV.`,
+	Example: `  List the snapshot of a virtual machine:
+  makeSnapshot list -m myVirtualMachine
+
+  List the snapshots of all web servers as JSON:
+  makeSnapshot list -m 'web*' --match glob -o json
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		switch listOutput {
+		case "table", "json", "csv":
+		default:
+			return fmt.Errorf("unknown output format %q, use table, json or csv", listOutput)
+		}
+
+		ctx, client, targets, err := lookupMachines(cmd, "Listing snapshots of")
+		if err != nil {
+			return err
+		}
+
+		var results []machineSnapshots
+		var failed []machineResult
+		for _, target := range targets {
+			result := machineSnapshots{Machine: target.name, ID: target.id, Platform: target.platform, Snapshots: []vra.Snapshot{}}
+			err := target.err
+			if err == nil {
+				traceInfo("Step 3 - Get snapshots of " + target.name)
+				var resource *vra.CatalogResource
+				if resource, err = client.GetCatalogResource(ctx, target.id); err == nil {
+					result.Snapshots = append(result.Snapshots, resource.Snapshots()...)
+				}
+			}
+			if err != nil {
+				log.Printf("%s: Error: %s (exit code %d)", target.name, err, exitCode(err))
+				result.Error = err.Error()
+				failed = append(failed, machineResult{machine: target.name, err: err})
+			}
+			results = append(results, result)
+		}
+
+		if err := writeSnapshots(os.Stdout, listOutput, results); err != nil {
+			return err
+		}
+
+		switch {
+		case len(failed) == 0:
+			return nil
+		case len(targets) == 1:
+			return failed[0].err
+		}
+		return &machinesError{total: len(targets), failed: failed}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+
+	addMachineFlags(listCmd)
+	listCmd.Flags().StringVarP(&listOutput, "output", "o", "table", "output format: table, json or csv")
+}
+
+// machineSnapshots are the snapshots of a machine as listed by the list command
+type machineSnapshots struct {
+	Machine   string         `json:"machine"`
+	ID        string         `json:"id,omitempty"`
+	Platform  string         `json:"platform,omitempty"`
+	Snapshots []vra.Snapshot `json:"snapshots"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// writeSnapshots writes the snapshots in the output format, a machine without
+// snapshots is a row without snapshot in a table or CSV
+func writeSnapshots(w io.Writer, format string, results []machineSnapshots) error {
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	}
+
+	header := []string{"MACHINE", "ID", "PLATFORM", "SNAPSHOT", "CREATED", "AGE", "DESCRIPTION"}
+	var rows [][]string
+	for _, result := range results {
+		if result.Error != "" {
+			continue
+		}
+		if len(result.Snapshots) == 0 {
+			rows = append(rows, []string{result.Machine, result.ID, result.Platform, "", "", "", ""})
+		}
+		for _, snapshot := range result.Snapshots {
+			created, age := "", ""
+			if !snapshot.Created.IsZero() {
+				created = snapshot.Created.Format(time.RFC3339)
+				age = formatAge(time.Since(snapshot.Created))
+			}
+			rows = append(rows, []string{result.Machine, result.ID, result.Platform, snapshot.Name, created, age, snapshot.Description})
+		}
+	}
+
+	if format == "csv" {
+		writer := csv.NewWriter(w)
+		for i := range header {
+			header[i] = strings.ToLower(header[i])
+		}
+		writer.Write(header)
+		writer.WriteAll(rows)
+		return writer.Error()
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, row := range rows {
+		for i := range row {
+			if row[i] == "" {
+				row[i] = "-"
+			}
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,470 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/tIsGoud/makeSnapshot/pkg/vra"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// selectedMachines returns the machine names from the 'machineName' flags and
+// the machines file, "-" reads the machines file from stdin. Duplicates are
+// removed. The selectors are read from the --resource-id, --ip, --hostname
+// and --property flags.
+func selectedMachines() ([]string, []vra.Selector, error) {
+	machines := append([]string{}, machineNames...)
+
+	if machinesFile != "" {
+		var reader io.Reader = os.Stdin
+		if machinesFile != "-" {
+			file, err := os.Open(machinesFile)
+			if err != nil {
+				return nil, nil, newConfigError("unable to read machines file: %s", err)
+			}
+			defer file.Close()
+			reader = file
+		}
+
+		fromFile, err := readMachines(reader)
+		if err != nil {
+			return nil, nil, newConfigError("unable to read machines file: %s", err)
+		}
+		machines = append(machines, fromFile...)
+	}
+
+	seen := make(map[string]bool)
+	var unique []string
+	for _, machine := range machines {
+		machine = strings.TrimSpace(machine)
+		if machine == "" || seen[machine] {
+			continue
+		}
+		seen[machine] = true
+		unique = append(unique, machine)
+	}
+
+	selectors, err := machineSelectors()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(unique) == 0 && len(selectors) == 0 {
+		return nil, nil, newConfigError("no virtual machine selected, use --machineName, --machines-file, --resource-id, --ip, --hostname or --property")
+	}
+	return unique, selectors, nil
+}
+
+// machineSelectors returns the selectors of the --resource-id, --ip,
+// --hostname and --property flags. Duplicates are removed.
+func machineSelectors() ([]vra.Selector, error) {
+	flags := []struct {
+		kind   vra.SelectorKind
+		values []string
+	}{
+		{vra.SelectResourceID, resourceIDs},
+		{vra.SelectIP, ipAddresses},
+		{vra.SelectHostname, hostnames},
+		{vra.SelectProperty, properties},
+	}
+
+	seen := make(map[vra.Selector]bool)
+	var selectors []vra.Selector
+	for _, flag := range flags {
+		for _, value := range flag.values {
+			selector, err := vra.ParseSelector(flag.kind, value)
+			if err != nil {
+				return nil, newConfigError("%s", err)
+			}
+			if !seen[selector] {
+				seen[selector] = true
+				selectors = append(selectors, selector)
+			}
+		}
+	}
+	return selectors, nil
+}
+
+// machineLabels returns the machine names and selectors as shown in the tracing
+func machineLabels(machines []string, selectors []vra.Selector) []string {
+	labels := append([]string{}, machines...)
+	for _, selector := range selectors {
+		labels = append(labels, selector.String())
+	}
+	return labels
+}
+
+// readMachines reads one machine name per line, empty lines and lines
+// starting with '#' are skipped.
+func readMachines(reader io.Reader) ([]string, error) {
+	var machines []string
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		machines = append(machines, line)
+	}
+	return machines, scanner.Err()
+}
+
+// machineTarget is a virtual machine selected in Step 2. A machine name that
+// is not found in the catalog, or is ambiguous, has an error instead of a
+// resource id.
+type machineTarget struct {
+	name     string
+	id       string
+	platform string
+	resource vra.CatalogResource
+	err      error
+}
+
+// machineTargets returns the selected machines in the order of the machine
+// names. An exact name keeps the name as given, a pattern is replaced by the
+// names of the matching resources. The suggestions are used for the machine
+// names that are not found.
+func machineTargets(machines []string, resources map[string][]vra.CatalogResource, matchMode vra.MatchMode, suggestions map[string][]string) []machineTarget {
+	var targets []machineTarget
+	seen := make(map[string]bool)
+	for _, machine := range machines {
+		matches := resources[machine]
+		switch {
+		case len(matches) == 0:
+			err := &vra.MachineNotFoundError{Machine: machine, Suggestions: suggestions[machine]}
+			targets = append(targets, machineTarget{name: machine, err: err})
+			continue
+		case len(matches) > 1 && matchMode == vra.MatchExact:
+			err := &vra.AmbiguousMachineError{Machine: machine, Candidates: matches}
+			targets = append(targets, machineTarget{name: machine, err: err})
+			continue
+		}
+
+		for _, resource := range matches {
+			// Overlapping patterns select a machine only once
+			if seen[resource.ID] {
+				continue
+			}
+			seen[resource.ID] = true
+
+			name := resource.Name
+			if matchMode == vra.MatchExact {
+				name = machine
+			}
+			targets = append(targets, machineTarget{name: name, id: resource.ID, platform: resource.Platform(), resource: resource})
+		}
+	}
+	return targets
+}
+
+// selectorTargets returns the machines selected by the selectors in the order
+// of the selectors, using the resource names. A unique selector, like an IP
+// address, that matches more than one machine is ambiguous.
+func selectorTargets(selectors []vra.Selector, resources map[vra.Selector][]vra.CatalogResource) []machineTarget {
+	var targets []machineTarget
+	for _, selector := range selectors {
+		matches := resources[selector]
+		switch {
+		case len(matches) == 0:
+			err := &vra.MachineNotFoundError{Machine: selector.String()}
+			targets = append(targets, machineTarget{name: selector.String(), err: err})
+			continue
+		case len(matches) > 1 && selector.Unique():
+			err := &vra.AmbiguousMachineError{Machine: selector.String(), Candidates: matches}
+			targets = append(targets, machineTarget{name: selector.String(), err: err})
+			continue
+		}
+
+		for _, resource := range matches {
+			targets = append(targets, machineTarget{name: resource.Name, id: resource.ID, platform: resource.Platform(), resource: resource})
+		}
+	}
+	return targets
+}
+
+// uniqueTargets removes the machines that are selected more than once, e.g.
+// by name and by IP address
+func uniqueTargets(targets []machineTarget) []machineTarget {
+	seen := make(map[string]bool)
+	var unique []machineTarget
+	for _, target := range targets {
+		if target.err == nil {
+			if seen[target.id] {
+				continue
+			}
+			seen[target.id] = true
+		}
+		unique = append(unique, target)
+	}
+	return unique
+}
+
+// confirmTargets shows the selected machines and asks for confirmation when
+// more than one machine is selected, unless --yes is used. When noChanges is
+// set, e.g. on a dry-run, the machines are shown without a confirmation.
+func confirmTargets(ctx context.Context, targets []machineTarget, action string, noChanges bool) error {
+	var selected []machineTarget
+	for _, target := range targets {
+		if target.err == nil {
+			selected = append(selected, target)
+		}
+	}
+	if len(selected) <= 1 {
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Selected %d virtual machines:\n", len(selected))
+	for _, target := range selected {
+		fmt.Fprintf(os.Stderr, "  %s (%s)\n", target.name, target.id)
+	}
+	if assumeYes || noChanges {
+		return nil
+	}
+
+	confirmed, err := confirm(ctx, fmt.Sprintf("%s %d virtual machines?", action, len(selected)))
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		return errNotConfirmed
+	}
+	return nil
+}
+
+// errNotConfirmed is returned when the selected machines are not confirmed
+var errNotConfirmed = &classError{code: exitAborted, err: errors.New("not confirmed, use --yes to skip the confirmation")}
+
+// stdin is shared by all confirmations, a buffered reader per question would
+// lose the answers of piped input
+var stdin = bufio.NewReader(os.Stdin)
+
+// errStdinInUse is returned when a confirmation is needed while the machine
+// names are read from stdin, there is no input left for the answer
+var errStdinInUse = newConfigError("unable to ask for confirmation, use --yes when reading the machines from stdin")
+
+// confirm asks the question and tells whether it is answered with yes. An
+// interrupt while waiting for the answer returns the error of the context.
+func confirm(ctx context.Context, question string) (bool, error) {
+	if machinesFile == "-" {
+		return false, errStdinInUse
+	}
+
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answers := make(chan string, 1)
+	go func() {
+		answer, _ := stdin.ReadString('\n')
+		answers <- answer
+	}()
+
+	select {
+	case <-ctx.Done():
+		fmt.Fprintln(os.Stderr)
+		return false, ctx.Err()
+	case answer := <-answers:
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "y", "yes":
+			return true, nil
+		}
+		return false, nil
+	}
+}
+
+// machineResult is the outcome of the snapshot of one machine
+type machineResult struct {
+	machine string
+	id      string
+	err     error
+}
+
+// machinesError is returned when the snapshot of one or more machines failed
+type machinesError struct {
+	total  int
+	failed []machineResult
+}
+
+func (e *machinesError) Error() string {
+	return fmt.Sprintf("%d of %d virtual machines failed", len(e.failed), e.total)
+}
+
+// exitCode returns the exit status code of the failures when they all have
+// the same code, otherwise exitMultipleFailures.
+func (e *machinesError) exitCode() int {
+	code := exitCode(e.failed[0].err)
+	for _, result := range e.failed[1:] {
+		if exitCode(result.err) != code {
+			return exitMultipleFailures
+		}
+	}
+	return code
+}
+
+// lookupMachines validates the config, requests one bearer token for all
+// machines and looks up the selected machines, the steps 1 and 2. The
+// progress describes the command in the tracing, e.g. "Creating snapshot of".
+func lookupMachines(cmd *cobra.Command, progress string) (context.Context, *vra.Client, []machineTarget, error) {
+	// From here on a failure is not a usage error
+	cmd.SilenceUsage = true
+
+	if err := validateConfig(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	machines, selectors, err := selectedMachines()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	traceInfo(progress + ` virtual machine(s) "` + strings.Join(machineLabels(machines, selectors), `", "`) + `" for tenant "` + viper.GetString("tenant") + `"`)
+
+	ctx := signalContext()
+	client := newClient()
+
+	// Step 1 - Get bearer token (POST {baseURL}/identity/api/tokens), one token for all machines
+	if err := getBearerToken(ctx, client); err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Step 2 - Get VirtualMachine Resource id  (GET {baseURL}/catalog-service/api/consumer/resources?page={page}&limit={catalogPageSize}&$filter={filter}), one catalog scan for all names and selectors
+	targets, err := getVirtualMachineTargets(ctx, client, machines, selectors)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return ctx, client, targets, nil
+}
+
+// machineCommand is a command that handles the selected machines one by one
+type machineCommand struct {
+	// progress describes the command in the tracing, e.g. "Creating snapshot of"
+	progress string
+
+	// action is used in the confirmation, e.g. "Snapshot"
+	action string
+
+	// confirm replaces the confirmation of the selected machines when set,
+	// it returns the machines to handle
+	confirm func(ctx context.Context, client *vra.Client, targets []machineTarget) ([]machineTarget, error)
+
+	// run handles a single machine
+	run func(ctx context.Context, client *vra.Client, target machineTarget) error
+}
+
+// runOnMachines runs the command for every selected machine. The config is
+// validated, one bearer token is requested and the machines are looked up once.
+func runOnMachines(cmd *cobra.Command, command machineCommand) error {
+	if err := validateResultOutput(); err != nil {
+		return err
+	}
+
+	ctx, client, targets, err := lookupMachines(cmd, command.progress)
+	if err != nil {
+		return err
+	}
+
+	// More than one machine requires a confirmation, a dry-run changes nothing
+	if command.confirm != nil {
+		if targets, err = command.confirm(ctx, client, targets); err != nil {
+			return err
+		}
+	} else if err := confirmTargets(ctx, targets, command.action, dryRun); err != nil {
+		return err
+	}
+
+	// Step 3 to 6 per machine
+	results, err := forEachMachine(ctx, targets, func(ctx context.Context, target machineTarget) error {
+		if target.err != nil {
+			return target.err
+		}
+		return command.run(ctx, client, target)
+	})
+	if resultOutput == "json" {
+		if err := writeResults(os.Stdout, results); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// Silly message at the end of the program
+	traceInfo("Bye from makeSnapshot")
+	return nil
+}
+
+// forEachMachine runs fn for every machine with at most 'parallel' machines at
+// the same time and reports the result per machine. With a single machine
+// the error of that machine is returned as is.
+func forEachMachine(ctx context.Context, targets []machineTarget, fn func(ctx context.Context, target machineTarget) error) ([]machineResult, error) {
+	if len(targets) == 1 {
+		result := machineResult{machine: targets[0].name, id: targets[0].id, err: fn(ctx, targets[0])}
+		return []machineResult{result}, result.err
+	}
+
+	limit := viper.GetInt("parallel")
+	if limit < 1 {
+		limit = 1
+	}
+	semaphore := make(chan struct{}, limit)
+
+	results := make([]machineResult, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target machineTarget) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			results[i] = machineResult{machine: target.name, id: target.id, err: fn(ctx, target)}
+		}(i, target)
+	}
+	wg.Wait()
+
+	// Report the result per machine, in the order the machines were given
+	var failed []machineResult
+	for _, result := range results {
+		if result.err != nil {
+			log.Printf("%s: Error: %s (exit code %d)", result.machine, result.err, exitCode(result.err))
+			failed = append(failed, result)
+		} else {
+			log.Printf("%s: OK", result.machine)
+		}
+	}
+
+	if len(failed) > 0 {
+		// An interrupt stops all machines, report it as such
+		if errors.Is(ctx.Err(), context.Canceled) {
+			return results, ctx.Err()
+		}
+		return results, &machinesError{total: len(targets), failed: failed}
+	}
+	return results, nil
+}
@@ -0,0 +1,102 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/tIsGoud/makeSnapshot/pkg/vra"
+
+	"github.com/spf13/cobra"
+)
+
+// resultOutput is the output format of the results of the machine commands
+var resultOutput string
+
+// addResultOutputFlag adds the --output flag of the results to the command
+func addResultOutputFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(&resultOutput, "output", "o", "text", "output format of the results: text or json")
+}
+
+// validateResultOutput checks the --output flag
+func validateResultOutput() error {
+	switch resultOutput {
+	case "text", "json":
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q, use text or json", resultOutput)
+}
+
+// machineReport is the result of a machine in the JSON output
+type machineReport struct {
+	Machine  string         `json:"machine"`
+	ID       string         `json:"id,omitempty"`
+	Status   string         `json:"status"`
+	ExitCode int            `json:"exitCode"`
+	Error    string         `json:"error,omitempty"`
+	Request  *requestReport `json:"request,omitempty"`
+}
+
+// requestReport describes the failed vRA request of a machine, so the failure
+// can be investigated without access to the vRA portal
+type requestReport struct {
+	ID                string `json:"id"`
+	RequestNumber     int    `json:"requestNumber"`
+	State             string `json:"state"`
+	Phase             string `json:"phase,omitempty"`
+	CompletionState   string `json:"completionState,omitempty"`
+	CompletionDetails string `json:"completionDetails,omitempty"`
+	Reasons           string `json:"reasons,omitempty"`
+}
+
+// writeResults writes the results of the machines as JSON
+func writeResults(w io.Writer, results []machineResult) error {
+	reports := make([]machineReport, 0, len(results))
+	for _, result := range results {
+		report := machineReport{Machine: result.machine, ID: result.id, Status: "OK", ExitCode: exitCode(result.err)}
+		if result.err != nil {
+			report.Status = "Error"
+			report.Error = result.err.Error()
+		}
+
+		var failed *vra.RequestFailedError
+		if errors.As(result.err, &failed) && failed.Request != nil {
+			request := failed.Request
+			report.Request = &requestReport{
+				ID:                request.ID,
+				RequestNumber:     request.RequestNumber,
+				State:             request.StateName,
+				Phase:             request.Phase,
+				CompletionState:   request.RequestCompletion.RequestCompletionState,
+				CompletionDetails: request.RequestCompletion.CompletionDetails,
+				Reasons:           request.Reasons,
+			}
+		}
+		reports = append(reports, report)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(reports)
+}
@@ -0,0 +1,65 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/tIsGoud/makeSnapshot/pkg/vra"
+
+	"github.com/spf13/cobra"
+)
+
+// revertCmd represents the revert command
+var revertCmd = &cobra.Command{
+	Use:   "revert",
+	Short: "Revert a virtual machine to its snapshot",
+	Long: `
+The revert option reverts a virtual machine to its snapshot with the
+"Revert To Snapshot" day-2 action, e.g. to roll back a failed upgrade.
+
+The machines are selected like with the snapshot, the request status is
+checked until the revert is finished. Use '--dry-run' to check the
+selection and the action without reverting.`,
+	Example: `  Revert a virtual machine, with tracing information:
+  makeSnapshot revert -m myVirtualMachineToRevert -t
+
+  Check the selection without reverting:
+  makeSnapshot revert -m 'web*' --match glob --dry-run
+`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runOnMachines(cmd, machineCommand{progress: "Reverting", action: "Revert", run: revertMachine})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(revertCmd)
+
+	addMachineFlags(revertCmd)
+	addRequestFlags(revertCmd)
+	addResultOutputFlag(revertCmd)
+}
+
+// revertMachine runs the steps 3 to 6 of the revert for a single machine
+func revertMachine(ctx context.Context, client *vra.Client, target machineTarget) error {
+	return runResourceAction(ctx, client, target, "revert", configuredAction(vra.RevertSnapshotAction, "revertAction"))
+}
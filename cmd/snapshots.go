@@ -0,0 +1,161 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/tIsGoud/makeSnapshot/pkg/vra"
+
+	"github.com/spf13/viper"
+)
+
+// Default templates of the snapshot name and description
+const (
+	defaultSnapshotName        = `{{.Machine}}-{{.Timestamp.Format "20060102-150405"}}`
+	defaultSnapshotDescription = `makeSnapshot by {{.User}} on {{.Timestamp.Format "2006-01-02 15:04:05"}}`
+)
+
+// startTime is the timestamp of the snapshots, the same for all machines of a run
+var startTime = time.Now()
+
+// snapshotTemplates are the parsed templates of the snapshot name and description
+type snapshotTemplates struct {
+	name        *template.Template
+	description *template.Template
+}
+
+// snapshotTemplateData is available in the snapshot name and description
+// templates, e.g. {{.Machine}} or {{.Env.BUILD_NUMBER}}
+type snapshotTemplateData struct {
+	Machine   string
+	Timestamp time.Time
+	Env       map[string]string
+	User      string
+}
+
+// parseSnapshotTemplates parses the snapshot name and description templates
+// of the --name and --description flags or the config file
+func parseSnapshotTemplates() (*snapshotTemplates, error) {
+	// A missing environment variable is empty instead of "<no value>"
+	name, err := template.New("name").Option("missingkey=zero").Parse(viper.GetString("snapshotName"))
+	if err != nil {
+		return nil, newConfigError("invalid snapshot name template: %s", err)
+	}
+	description, err := template.New("description").Option("missingkey=zero").Parse(viper.GetString("snapshotDescription"))
+	if err != nil {
+		return nil, newConfigError("invalid snapshot description template: %s", err)
+	}
+
+	// Fail before logging in, e.g. on an unknown field
+	templates := &snapshotTemplates{name: name, description: description}
+	if _, _, err := templates.execute("machine"); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+// execute returns the snapshot name and description of the machine
+func (t *snapshotTemplates) execute(machine string) (string, string, error) {
+	data := snapshotTemplateData{
+		Machine:   machine,
+		Timestamp: startTime,
+		Env:       make(map[string]string),
+		User:      currentUser(),
+	}
+	for _, variable := range os.Environ() {
+		if parts := strings.SplitN(variable, "=", 2); len(parts) == 2 {
+			data.Env[parts[0]] = parts[1]
+		}
+	}
+
+	var name, description bytes.Buffer
+	if err := t.name.Execute(&name, data); err != nil {
+		return "", "", newConfigError("invalid snapshot name template: %s", err)
+	}
+	if err := t.description.Execute(&description, data); err != nil {
+		return "", "", newConfigError("invalid snapshot description template: %s", err)
+	}
+	if strings.TrimSpace(name.String()) == "" {
+		return "", "", newConfigError("the snapshot name template results in an empty name")
+	}
+	return name.String(), description.String(), nil
+}
+
+// currentUser returns the name of the user running the application
+func currentUser() string {
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	return os.Getenv("USER")
+}
+
+// checkExistingSnapshots fails when the machine already has a snapshot, with
+// --keepExisting the snapshot request would fail in vRA minutes later
+func checkExistingSnapshots(ctx context.Context, client *vra.Client, machine, vmID string) error {
+	traceInfo("Step 4 - Check existing snapshots of " + machine)
+
+	resource, err := client.GetCatalogResource(ctx, vmID)
+	if err != nil {
+		return err
+	}
+	if snapshots := resource.Snapshots(); len(snapshots) > 0 {
+		return &classError{code: exitSnapshotExists, err: fmt.Errorf("snapshot %s of %s already exists and --keepExisting is set", describeSnapshots(snapshots), machine)}
+	}
+	return nil
+}
+
+// describeSnapshots returns the names and ages of the snapshots, like
+// "before-upgrade" (created 2019-05-29 01:33, 2d4h ago)
+func describeSnapshots(snapshots []vra.Snapshot) string {
+	var descriptions []string
+	for _, snapshot := range snapshots {
+		description := fmt.Sprintf("%q", snapshot.Name)
+		if !snapshot.Created.IsZero() {
+			description += fmt.Sprintf(" (created %s, %s ago)", snapshot.Created.Local().Format("2006-01-02 15:04"), formatAge(time.Since(snapshot.Created)))
+		}
+		descriptions = append(descriptions, description)
+	}
+	return strings.Join(descriptions, ", ")
+}
+
+// formatAge returns the age in days, hours and minutes, like "2d4h" or "35m"
+func formatAge(age time.Duration) string {
+	age = age.Round(time.Minute)
+	days := age / (24 * time.Hour)
+	hours := (age % (24 * time.Hour)) / time.Hour
+	minutes := (age % time.Hour) / time.Minute
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
@@ -20,7 +20,7 @@
 
 package main
 
-import "makeSnapshot/cmd"
+import "github.com/tIsGoud/makeSnapshot/cmd"
 
 func main() {
 	cmd.Execute()
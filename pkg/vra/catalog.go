@@ -0,0 +1,279 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vra
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ErrNotFound is returned when a resource or resource action can not be found
+var ErrNotFound = errors.New("vra: not found")
+
+// ErrActionNotFound is returned when the resource action is not available on
+// the resource, it is also an ErrNotFound
+var ErrActionNotFound = fmt.Errorf("%w: resource action", ErrNotFound)
+
+// ErrUnsupportedField is returned when a field is not in the request template of an action
+var ErrUnsupportedField = errors.New("vra: unsupported field")
+
+// UnsupportedFieldError is returned when a field is not in the request
+// template of an action, it is an ErrUnsupportedField. Fields holds the
+// fields of the template.
+type UnsupportedFieldError struct {
+	Field  string
+	Fields []string
+}
+
+func (e *UnsupportedFieldError) Error() string {
+	return fmt.Sprintf("%s %q, the template has the fields %s", ErrUnsupportedField, e.Field, strings.Join(e.Fields, ", "))
+}
+
+func (e *UnsupportedFieldError) Unwrap() error {
+	return ErrUnsupportedField
+}
+
+// GetCatalogResources returns a page of the consumer catalog resources, the
+// optional filter is an OData $filter expression evaluated by vRA
+// (GET {baseURL}/catalog-service/api/consumer/resources?page={page}&limit={limit}&$filter={filter})
+func (c *Client) GetCatalogResources(ctx context.Context, page, limit int, filter string) (*CatalogResourcePage, error) {
+	query := url.Values{}
+	query.Set("page", strconv.Itoa(page))
+	query.Set("limit", strconv.Itoa(limit))
+	if filter != "" {
+		query.Set("$filter", filter)
+	}
+
+	req, err := c.newRequest(ctx, http.MethodGet, "/catalog-service/api/consumer/resources?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resources CatalogResourcePage
+	if err := c.getJSON(req, &resources); err != nil {
+		return nil, err
+	}
+	return &resources, nil
+}
+
+// GetCatalogResource returns a single consumer catalog resource with its
+// complete resource data, like the current snapshots of a machine
+// (GET {baseURL}/catalog-service/api/consumer/resources/{id})
+func (c *Client) GetCatalogResource(ctx context.Context, id string) (*CatalogResource, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/catalog-service/api/consumer/resources/"+id, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resource CatalogResource
+	if err := c.getJSON(req, &resource); err != nil {
+		return nil, err
+	}
+	return &resource, nil
+}
+
+// GetResourceActions returns the actions available on the resource
+// (GET {baseURL}/catalog-service/api/consumer/resources/{vmID}/actions/)
+func (c *Client) GetResourceActions(ctx context.Context, vmID string) ([]ResourceAction, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/catalog-service/api/consumer/resources/"+vmID+"/actions/", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var actions ResourceActionPage
+	if err := c.getJSON(req, &actions); err != nil {
+		return nil, err
+	}
+	return actions.Content, nil
+}
+
+// ActionSelector identifies a day-2 resource action. The action is found by
+// its stable binding, which does not change when an administrator renames
+// the action or the portal is localized. The names are only a fallback for
+// tenants that bind the action differently, e.g. with a custom XaaS action.
+type ActionSelector struct {
+	BindingID string   // binding id or extension id of the action
+	Names     []string // names of the action, compared case-insensitively
+}
+
+// CreateSnapshotAction is the "Create VM Snapshot" day-2 action of an IaaS machine
+var CreateSnapshotAction = ActionSelector{
+	BindingID: "Infrastructure.Machine.Action.CreateSnapshot",
+	Names:     []string{"Create VM Snapshot", "Create Snapshot"},
+}
+
+// RevertSnapshotAction is the "Revert To Snapshot" day-2 action of an IaaS machine
+var RevertSnapshotAction = ActionSelector{
+	BindingID: "Infrastructure.Machine.Action.RevertSnapshot",
+	Names:     []string{"Revert To Snapshot", "Revert Snapshot"},
+}
+
+// DeleteSnapshotAction is the "Delete Snapshot" day-2 action of an IaaS machine
+var DeleteSnapshotAction = ActionSelector{
+	BindingID: "Infrastructure.Machine.Action.DeleteSnapshot",
+	Names:     []string{"Delete Snapshot", "Delete VM Snapshot"},
+}
+
+func (a ActionSelector) String() string {
+	if len(a.Names) > 0 {
+		return a.Names[0]
+	}
+	return a.BindingID
+}
+
+// ActionNotFoundError is returned when the resource action is not available
+// on the resource, it is an ErrActionNotFound. Available holds the actions
+// that are available on the resource.
+type ActionNotFoundError struct {
+	Action    ActionSelector
+	Available []ResourceAction
+}
+
+func (e *ActionNotFoundError) Error() string {
+	msg := fmt.Sprintf("%s %q (binding %s)", ErrActionNotFound, e.Action, e.Action.BindingID)
+	if len(e.Available) == 0 {
+		return msg + ", the resource has no actions"
+	}
+
+	var available []string
+	for _, action := range e.Available {
+		binding := action.BindingID
+		if binding == "" {
+			binding = action.ExtensionID
+		}
+		available = append(available, fmt.Sprintf("%q (binding %s)", action.Name, binding))
+	}
+	return msg + ", available actions: " + strings.Join(available, ", ")
+}
+
+func (e *ActionNotFoundError) Unwrap() error {
+	return ErrActionNotFound
+}
+
+// GetResourceActionID returns the id of the resource action, matched by its
+// binding and otherwise by one of its names. An action that is not available
+// results in an *ActionNotFoundError.
+func (c *Client) GetResourceActionID(ctx context.Context, vmID string, selector ActionSelector) (string, error) {
+	actions, err := c.GetResourceActions(ctx, vmID)
+	if err != nil {
+		return "", err
+	}
+
+	var candidates []ResourceAction
+	for _, action := range actions {
+		if action.OperationType == "ACTION" && strings.TrimSpace(action.ID) != "" {
+			candidates = append(candidates, action)
+		}
+	}
+
+	if selector.BindingID != "" {
+		for _, action := range candidates {
+			if action.BindingID == selector.BindingID || action.ExtensionID == selector.BindingID {
+				return action.ID, nil
+			}
+		}
+	}
+	for _, name := range selector.Names {
+		for _, action := range candidates {
+			if strings.EqualFold(strings.TrimSpace(action.Name), strings.TrimSpace(name)) {
+				c.tracef("Resource action %q found by its name, its binding is %q", action.Name, action.BindingID)
+				return action.ID, nil
+			}
+		}
+	}
+	return "", &ActionNotFoundError{Action: selector, Available: actions}
+}
+
+// GetSnapshotResourceActionID returns the id of the "Create VM Snapshot" resource action
+func (c *Client) GetSnapshotResourceActionID(ctx context.Context, vmID string) (string, error) {
+	return c.GetResourceActionID(ctx, vmID, CreateSnapshotAction)
+}
+
+// GetResourceActionTemplate returns the request template of the snapshot action
+// (GET {baseURL}/catalog-service/api/consumer/resources/{vmID}/actions/{actionID}/requests/template)
+func (c *Client) GetResourceActionTemplate(ctx context.Context, vmID, snapshotActionID string) (*SnapShotTemplate, error) {
+	var template SnapShotTemplate
+	if err := c.getResourceActionTemplate(ctx, vmID, snapshotActionID, &template); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// GetResourceActionRequestTemplate returns the request template of any resource action
+// (GET {baseURL}/catalog-service/api/consumer/resources/{vmID}/actions/{actionID}/requests/template)
+func (c *Client) GetResourceActionRequestTemplate(ctx context.Context, vmID, actionID string) (*ResourceActionTemplate, error) {
+	var template ResourceActionTemplate
+	if err := c.getResourceActionTemplate(ctx, vmID, actionID, &template); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+func (c *Client) getResourceActionTemplate(ctx context.Context, vmID, actionID string, template interface{}) error {
+	req, err := c.newRequest(ctx, http.MethodGet, "/catalog-service/api/consumer/resources/"+vmID+"/actions/"+actionID+"/requests/template", nil)
+	if err != nil {
+		return err
+	}
+	return c.getJSON(req, template)
+}
+
+// SendSnapshotRequest submits the (filled in) template and returns the URL of the request status
+// (POST {baseURL}/catalog-service/api/consumer/resources/{vmID}/actions/{actionID}/requests/)
+func (c *Client) SendSnapshotRequest(ctx context.Context, vmID, snapshotActionID string, template *SnapShotTemplate) (string, error) {
+	return c.sendResourceActionRequest(ctx, vmID, snapshotActionID, template)
+}
+
+// SendResourceActionRequest submits the (filled in) template of any resource
+// action and returns the URL of the request status
+// (POST {baseURL}/catalog-service/api/consumer/resources/{vmID}/actions/{actionID}/requests/)
+func (c *Client) SendResourceActionRequest(ctx context.Context, vmID, actionID string, template *ResourceActionTemplate) (string, error) {
+	return c.sendResourceActionRequest(ctx, vmID, actionID, template)
+}
+
+func (c *Client) sendResourceActionRequest(ctx context.Context, vmID, actionID string, template interface{}) (string, error) {
+	jsonValue, err := json.Marshal(template)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, "/catalog-service/api/consumer/resources/"+vmID+"/actions/"+actionID+"/requests/", bytes.NewBuffer(jsonValue))
+	if err != nil {
+		return "", err
+	}
+
+	resp, _, err := c.do(req, http.StatusCreated)
+	if err != nil {
+		return "", err
+	}
+
+	location := resp.Header.Get("Location")
+	if strings.TrimSpace(location) == "" {
+		return "", errors.New("vra: empty resource action request URL")
+	}
+	return location, nil
+}
@@ -0,0 +1,95 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vra
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newActionServer starts a fake catalog that serves the resource actions of vm-1
+func newActionServer(t *testing.T, actions ...ResourceAction) *Client {
+	t.Helper()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/catalog-service/api/consumer/resources/vm-1/actions/" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(ResourceActionPage{Content: actions})
+	}))
+	t.Cleanup(s.Close)
+	return newTestClient(s.URL, 10, 1)
+}
+
+func TestGetResourceActionID(t *testing.T) {
+	bound := ResourceAction{ID: "a-bound", Name: "Snapshot maken", OperationType: "ACTION", BindingID: CreateSnapshotAction.BindingID}
+	named := ResourceAction{ID: "a-named", Name: " create vm snapshot ", OperationType: "ACTION", BindingID: "custom.snapshot"}
+	extension := ResourceAction{ID: "a-extension", Name: "Snapshot", OperationType: "ACTION", ExtensionID: CreateSnapshotAction.BindingID}
+	noID := ResourceAction{Name: "Create VM Snapshot", OperationType: "ACTION", BindingID: CreateSnapshotAction.BindingID}
+
+	tests := []struct {
+		name    string
+		actions []ResourceAction
+		want    string
+	}{
+		{"binding wins over name", []ResourceAction{named, bound}, "a-bound"},
+		{"case-insensitive name", []ResourceAction{named}, "a-named"},
+		{"extension id as binding", []ResourceAction{named, extension}, "a-extension"},
+		{"action without id", []ResourceAction{noID, named}, "a-named"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newActionServer(t, tt.actions...)
+			id, err := c.GetResourceActionID(context.Background(), "vm-1", CreateSnapshotAction)
+			if err != nil || id != tt.want {
+				t.Errorf("GetResourceActionID = %q, %v, want %q", id, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetResourceActionIDNotFound(t *testing.T) {
+	power := ResourceAction{ID: "a-power", Name: "Power Off", OperationType: "ACTION", BindingID: "Infrastructure.Machine.Action.PowerOff"}
+	custom := ResourceAction{ID: "a-custom", Name: "Backup", OperationType: "ACTION", ExtensionID: "com.example.backup"}
+	c := newActionServer(t, power, custom)
+
+	_, err := c.GetResourceActionID(context.Background(), "vm-1", CreateSnapshotAction)
+	var notFound *ActionNotFoundError
+	if !errors.As(err, &notFound) || !errors.Is(err, ErrActionNotFound) || !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetResourceActionID = %v, want an *ActionNotFoundError", err)
+	}
+	for _, want := range []string{`"Power Off" (binding Infrastructure.Machine.Action.PowerOff)`, `"Backup" (binding com.example.backup)`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not list %s", err, want)
+		}
+	}
+
+	c = newActionServer(t)
+	_, err = c.GetResourceActionID(context.Background(), "vm-1", CreateSnapshotAction)
+	if err == nil || !strings.HasSuffix(err.Error(), "the resource has no actions") {
+		t.Errorf("GetResourceActionID without actions = %v, want the resource has no actions", err)
+	}
+}
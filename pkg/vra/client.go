@@ -0,0 +1,216 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package vra is a small client for the vRealize Automation (vRA) APIs used to
+// create a snapshot of a virtual machine.
+//
+// The client is configured explicitly, it does not read any global
+// configuration, and every call takes a context and returns an error instead
+// of terminating the program.
+package vra
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// DefaultUserAgent is sent with every HTTP request unless Client.UserAgent is set
+const DefaultUserAgent = "makeSnapshot vra-client"
+
+// Client talks to a single vRA tenant
+type Client struct {
+	// BaseURL of the vRA platform, e.g. "https://vra.example.com"
+	BaseURL string
+
+	// Tenant name used to log in and in the snapshot request
+	Tenant string
+
+	// UserAgent used in the HTTP requests
+	UserAgent string
+
+	// HTTPClient used for all requests, http.DefaultClient when nil
+	HTTPClient *http.Client
+
+	// PageSize is the number of catalog resources requested per page
+	PageSize int
+
+	// PageWorkers is the maximum number of catalog pages fetched concurrently
+	PageWorkers int
+
+	// IncludeCloudMachines also looks up cloud machines (Infrastructure.Cloud)
+	IncludeCloudMachines bool
+
+	// Platforms restricts the machine lookup to these platforms, e.g.
+	// "vSphere" or "HyperV", all platforms when empty
+	Platforms []string
+
+	// Tracef is called with progress information when set
+	Tracef func(format string, v ...interface{})
+
+	// bearerToken is the id of the token returned by GetBearerToken
+	bearerToken string
+}
+
+// NewClient returns a client for the given baseURL and tenant
+func NewClient(baseURL, tenant string) *Client {
+	return &Client{
+		BaseURL:     strings.TrimRight(baseURL, "/"),
+		Tenant:      tenant,
+		UserAgent:   DefaultUserAgent,
+		HTTPClient:  &http.Client{},
+		PageSize:    DefaultPageSize,
+		PageWorkers: DefaultPageWorkers,
+	}
+}
+
+// SetBearerToken sets the token id used in the Authorization header,
+// e.g. a token that was obtained earlier with GetBearerToken.
+func (c *Client) SetBearerToken(id string) {
+	c.bearerToken = id
+}
+
+// BearerToken returns the token id currently in use
+func (c *Client) BearerToken() string {
+	return c.bearerToken
+}
+
+// tracef passes progress information to the Tracef function of the client
+func (c *Client) tracef(format string, v ...interface{}) {
+	if c.Tracef != nil {
+		c.Tracef(format, v...)
+	}
+}
+
+// APIError is returned when vRA responds with an unexpected HTTP status code
+type APIError struct {
+	StatusCode int
+	Message    string
+	Errors     []ErrorDetail
+}
+
+// ErrUnauthorized is returned when the credentials or the bearer token are not accepted
+var ErrUnauthorized = errors.New("vra: unauthorized")
+
+// Is reports an HTTP 401 or 403 response as ErrUnauthorized
+func (e *APIError) Is(target error) bool {
+	return target == ErrUnauthorized && (e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden)
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("unexpected HTTP response status code %d", e.StatusCode)
+	}
+	return fmt.Sprintf("unexpected HTTP response status code %d, %s", e.StatusCode, e.Message)
+}
+
+var htmlTitleRegex = regexp.MustCompile(`<h1>(.*)</h1>`)
+
+// newAPIError extracts the most useful message from an error response body,
+// vRA returns either a JSON error envelope or a HTML error page.
+func newAPIError(statusCode int, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: statusCode}
+
+	var errorResponse ErrorResponse
+	if err := json.Unmarshal(body, &errorResponse); err == nil && len(errorResponse.Errors) > 0 {
+		apiErr.Errors = errorResponse.Errors
+		apiErr.Message = errorResponse.Errors[0].SystemMessage
+		if apiErr.Message == "" {
+			apiErr.Message = errorResponse.Errors[0].Message
+		}
+	} else if matches := htmlTitleRegex.FindSubmatch(body); matches != nil {
+		apiErr.Message = string(matches[1])
+	}
+	return apiErr
+}
+
+// newRequest creates a JSON request with the default headers, relative paths
+// are resolved against the BaseURL.
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	url := path
+	if strings.HasPrefix(path, "/") {
+		url = c.BaseURL + path
+	}
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+
+	// Headers
+	req.Header.Add("Content-Type", "application/json;charset=UTF-8")
+	req.Header.Add("Accept", "application/json;charset=UTF-8")
+	if c.bearerToken != "" {
+		req.Header.Add("Authorization", "Bearer "+c.bearerToken)
+	}
+	userAgent := c.UserAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	return req, nil
+}
+
+// do sends the request and returns the response with the full body read,
+// a status code other than one of expectedStatus results in an *APIError.
+func (c *Client) do(req *http.Request, expectedStatus ...int) (*http.Response, []byte, error) {
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	// Fetch Request and handle possible connection errors
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	// Read Response Body
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp, nil, err
+	}
+
+	// Handle HTTP response status not in expectedStatus
+	for _, status := range expectedStatus {
+		if resp.StatusCode == status {
+			return resp, respBody, nil
+		}
+	}
+	return resp, respBody, newAPIError(resp.StatusCode, respBody)
+}
+
+// getJSON sends the request and decodes the JSON response body into v
+func (c *Client) getJSON(req *http.Request, v interface{}) error {
+	_, respBody, err := c.do(req, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(respBody, v)
+}
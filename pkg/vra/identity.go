@@ -0,0 +1,146 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vra
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// GetBearerToken logs in to the identity service (POST {baseURL}/identity/api/tokens)
+// and uses the returned token for all subsequent requests of the client.
+// The username is the fully qualified login, e.g. "user@domain".
+func (c *Client) GetBearerToken(ctx context.Context, username, password string) (*GetBearerTokenResponse, error) {
+	requestVars := GetBearerTokenRequest{
+		Username: username,
+		Password: password,
+		Tenant:   c.Tenant,
+	}
+
+	jsonValue, err := json.Marshal(requestVars)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, "/identity/api/tokens", bytes.NewBuffer(jsonValue))
+	if err != nil {
+		return nil, err
+	}
+
+	_, respBody, err := c.do(req, http.StatusOK)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest {
+		// vRA answers invalid credentials with a 400 Bad Request, a 401 or
+		// 403 already is an ErrUnauthorized
+		return nil, fmt.Errorf("%w: %s", ErrUnauthorized, apiErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var gbtResponse GetBearerTokenResponse
+	if err := json.Unmarshal(respBody, &gbtResponse); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(gbtResponse.ID) == "" {
+		return nil, errors.New("vra: empty bearer token in response")
+	}
+
+	c.bearerToken = gbtResponse.ID
+
+	return &gbtResponse, nil
+}
+
+// ValidateBearerToken checks with the identity service whether the token is still valid
+// (HEAD {baseURL}/identity/api/tokens/{id})
+func (c *Client) ValidateBearerToken(ctx context.Context, id string) (bool, error) {
+	req, err := c.newRequest(ctx, http.MethodHead, "/identity/api/tokens/"+url.PathEscape(id), nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Authorization", "Bearer "+id)
+
+	_, _, err = c.do(req, http.StatusNoContent)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+			return false, nil
+		}
+	}
+	return err == nil, err
+}
+
+// GetCachedBearerToken uses the token from the cache as long as it is valid and
+// does not expire within TokenExpiryMargin, otherwise a new token is requested
+// with GetBearerToken and stored in the cache. The cached return value tells
+// whether the token was taken from the cache.
+func (c *Client) GetCachedBearerToken(ctx context.Context, cache *TokenCache, username, password string) (token *GetBearerTokenResponse, cached bool, err error) {
+	key := TokenCacheKey(c.BaseURL, c.Tenant, username)
+
+	if token, ok := cache.Get(key); ok {
+		expires, err := token.ExpiresAt()
+		if err == nil && time.Now().Add(TokenExpiryMargin).Before(expires) {
+			valid, err := c.ValidateBearerToken(ctx, token.ID)
+			if err == nil && valid {
+				c.bearerToken = token.ID
+				return token, true, nil
+			}
+		}
+		c.tracef("Cached bearer token expired or invalid, requesting a new token")
+	}
+
+	token, err = c.GetBearerToken(ctx, username, password)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := cache.Put(key, token); err != nil {
+		// A failing cache is no reason to stop, the token is valid
+		c.tracef("Unable to cache bearer token: %s", err)
+	}
+	return token, false, nil
+}
+
+// RevokeBearerToken invalidates the token of the client on the identity service
+// (DELETE {baseURL}/identity/api/tokens/{id})
+func (c *Client) RevokeBearerToken(ctx context.Context) error {
+	if c.bearerToken == "" {
+		return nil
+	}
+
+	req, err := c.newRequest(ctx, http.MethodDelete, "/identity/api/tokens/"+url.PathEscape(c.bearerToken), nil)
+	if err != nil {
+		return err
+	}
+
+	if _, _, err := c.do(req, http.StatusNoContent); err != nil {
+		return err
+	}
+	c.bearerToken = ""
+	return nil
+}
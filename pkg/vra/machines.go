@@ -0,0 +1,513 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ErrMachineNotFound is returned when the virtual machine is not in the catalog, it is also an ErrNotFound
+var ErrMachineNotFound = fmt.Errorf("%w: virtual machine", ErrNotFound)
+
+// ErrAmbiguousMachine is returned when a machine name matches more than one virtual machine
+var ErrAmbiguousMachine = errors.New("vra: ambiguous virtual machine")
+
+// MachineNotFoundError is returned when the virtual machine is not in the
+// catalog, it is an ErrMachineNotFound. Suggestions holds the closest names
+// in the catalog, if any.
+type MachineNotFoundError struct {
+	Machine     string
+	Suggestions []string
+}
+
+func (e *MachineNotFoundError) Error() string {
+	msg := fmt.Sprintf("%s %q", ErrMachineNotFound, e.Machine)
+	if len(e.Suggestions) > 0 {
+		msg += ", did you mean " + quoteJoin(e.Suggestions) + "?"
+	}
+	return msg
+}
+
+func (e *MachineNotFoundError) Unwrap() error {
+	return ErrMachineNotFound
+}
+
+// AmbiguousMachineError is returned when a machine name matches more than one
+// virtual machine, it is an ErrAmbiguousMachine.
+type AmbiguousMachineError struct {
+	Machine    string
+	Candidates []CatalogResource
+}
+
+func (e *AmbiguousMachineError) Error() string {
+	var candidates []string
+	for _, candidate := range e.Candidates {
+		if platform := candidate.Platform(); platform != "" {
+			candidates = append(candidates, fmt.Sprintf("%q (%s, %s)", candidate.Name, candidate.ID, platform))
+		} else {
+			candidates = append(candidates, fmt.Sprintf("%q (%s)", candidate.Name, candidate.ID))
+		}
+	}
+	return fmt.Sprintf("%s %q matches %d machines: %s", ErrAmbiguousMachine, e.Machine, len(e.Candidates), strings.Join(candidates, ", "))
+}
+
+func (e *AmbiguousMachineError) Unwrap() error {
+	return ErrAmbiguousMachine
+}
+
+// quoteJoin quotes the values and joins them like "a", "b" or "c"
+func quoteJoin(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = fmt.Sprintf("%q", value)
+	}
+	if len(quoted) == 1 {
+		return quoted[0]
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " or " + quoted[len(quoted)-1]
+}
+
+// Resource types of the machines in the catalog
+const (
+	virtualMachineResourceType = "Infrastructure.Virtual"
+	cloudMachineResourceType   = "Infrastructure.Cloud"
+)
+
+// machineIconIDPrefix is followed by the platform in the icon id of a
+// machine, e.g. "Infrastructure.CatalogItem.Machine.Virtual.vSphere"
+const machineIconIDPrefix = "Infrastructure.CatalogItem.Machine."
+
+// machineInterfaceTypeKey is the resource data key of the machine platform
+const machineInterfaceTypeKey = "MachineInterfaceType"
+
+// Platform returns the platform of a machine, e.g. "vSphere", "HyperV" or
+// "KVM". It is read from the resource data and otherwise from the icon id.
+func (r *CatalogResource) Platform() string {
+	if platform := r.ResourceData.String(machineInterfaceTypeKey); platform != "" {
+		return platform
+	}
+	if strings.HasPrefix(r.IconID, machineIconIDPrefix) {
+		parts := strings.Split(r.IconID, ".")
+		return parts[len(parts)-1]
+	}
+	return ""
+}
+
+// machineResourceTypes returns the resource types of the machines to look up
+func (c *Client) machineResourceTypes() []string {
+	if c.IncludeCloudMachines {
+		return []string{virtualMachineResourceType, cloudMachineResourceType}
+	}
+	return []string{virtualMachineResourceType}
+}
+
+// isMachine tells whether the resource is a machine of one of the resource
+// types and platforms to look up
+func (c *Client) isMachine(resource CatalogResource) bool {
+	machineType := false
+	for _, resourceType := range c.machineResourceTypes() {
+		machineType = machineType || resource.ResourceTypeRef.ID == resourceType
+	}
+	if !machineType {
+		return false
+	}
+
+	if len(c.Platforms) == 0 {
+		return true
+	}
+	platform := resource.Platform()
+	for _, wanted := range c.Platforms {
+		if strings.EqualFold(platform, wanted) {
+			return true
+		}
+	}
+	return false
+}
+
+// machineTypeFilter returns the OData filter that selects the machine resource types
+func (c *Client) machineTypeFilter() string {
+	var types []string
+	for _, resourceType := range c.machineResourceTypes() {
+		types = append(types, "resourceType/id eq '"+resourceType+"'")
+	}
+	if len(types) == 1 {
+		return types[0]
+	}
+	return "(" + strings.Join(types, " or ") + ")"
+}
+
+// MatchMode tells how a machine name is compared with the catalog resource names
+type MatchMode string
+
+// Match modes
+const (
+	MatchExact MatchMode = "exact" // the name is equal to the machine name
+	MatchGlob  MatchMode = "glob"  // shell pattern like "web*" or "db0[1-3]"
+	MatchRegex MatchMode = "regex" // regular expression matching the full name
+)
+
+// ParseMatchMode returns the match mode for "exact", "glob" or "regex"
+func ParseMatchMode(mode string) (MatchMode, error) {
+	switch MatchMode(strings.ToLower(mode)) {
+	case MatchExact:
+		return MatchExact, nil
+	case MatchGlob:
+		return MatchGlob, nil
+	case MatchRegex:
+		return MatchRegex, nil
+	}
+	return "", fmt.Errorf("vra: unknown match mode %q, use exact, glob or regex", mode)
+}
+
+// MatchOptions controls how machine names are matched in the catalog. The
+// names and patterns are matched against the resource name without its
+// tenant prefix and against the fully qualified resource name, prefix included.
+type MatchOptions struct {
+	Mode       MatchMode
+	IgnoreCase bool
+	Prefix     TenantPrefix
+}
+
+// nameMatcher tells whether a catalog resource name matches a machine name or pattern
+type nameMatcher func(name string) bool
+
+// newNameMatcher returns the matcher of the pattern for the match options
+func newNameMatcher(pattern string, opts MatchOptions) (nameMatcher, error) {
+	var matchRemainder func(name string) bool
+
+	switch opts.Mode {
+	case MatchExact, "":
+		matchRemainder = func(name string) bool {
+			return name == pattern || (opts.IgnoreCase && strings.EqualFold(name, pattern))
+		}
+
+	case MatchGlob:
+		if opts.IgnoreCase {
+			pattern = strings.ToLower(pattern)
+		}
+		// Validate the pattern once, path.Match only reports a bad pattern on use
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("vra: invalid glob pattern %q: %w", pattern, err)
+		}
+		matchRemainder = func(name string) bool {
+			if opts.IgnoreCase {
+				name = strings.ToLower(name)
+			}
+			matched, _ := path.Match(pattern, name)
+			return matched
+		}
+
+	case MatchRegex:
+		expr := `^(?:` + pattern + `)$`
+		if opts.IgnoreCase {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("vra: invalid regex %q: %w", pattern, err)
+		}
+		matchRemainder = re.MatchString
+
+	default:
+		return nil, fmt.Errorf("vra: unknown match mode %q", opts.Mode)
+	}
+
+	// The fully qualified name is the remainder without prefix
+	return func(name string) bool {
+		if matchRemainder(name) {
+			return true
+		}
+		for _, remainder := range opts.Prefix.remainders(name, opts.IgnoreCase) {
+			if matchRemainder(remainder) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// fullNameMatch tells whether the resource name is the fully qualified machine name
+func fullNameMatch(name, machine string, opts MatchOptions) bool {
+	return name == machine || (opts.IgnoreCase && strings.EqualFold(name, machine))
+}
+
+// GetVirtualMachineResourceID returns the catalog resource id of the virtual machine
+// with the exact machine name, the match mode of the options is not used. A
+// name that matches more than one machine results in an *AmbiguousMachineError,
+// an unknown name in a *MachineNotFoundError.
+func (c *Client) GetVirtualMachineResourceID(ctx context.Context, machine string, opts MatchOptions) (string, error) {
+	opts.Mode = MatchExact
+	lookup, err := c.LookupVirtualMachines(ctx, []string{machine}, opts, nil, 3)
+	if err != nil {
+		return "", err
+	}
+
+	switch matches := lookup.Names[machine]; len(matches) {
+	case 0:
+		return "", &MachineNotFoundError{Machine: machine, Suggestions: lookup.Suggestions[machine]}
+	case 1:
+		return matches[0].ID, nil
+	default:
+		return "", &AmbiguousMachineError{Machine: machine, Candidates: matches}
+	}
+}
+
+// FindVirtualMachines looks up several machine names or patterns with a single
+// catalog scan and returns the matching catalog resources per name, in
+// catalog order. Names without a match are missing from the result. All
+// candidates are read, so an exact name with more than one match can be
+// reported as ambiguous. A fully qualified name is unique in vRA, it takes
+// precedence over the names without prefix and once all machines are found
+// by their fully qualified name the scan stops early.
+func (c *Client) FindVirtualMachines(ctx context.Context, patterns []string, opts MatchOptions) (map[string][]CatalogResource, error) {
+	names, err := newNameLookup(patterns, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	match := func(resources []CatalogResource) bool {
+		for _, resource := range resources {
+			names.match(resource)
+		}
+		return names.done()
+	}
+	reset := func(string) {
+		names.reset()
+	}
+	if err := c.scanVirtualMachines(ctx, machineFilter(c.machineTypeFilter(), patterns, opts), reset, match); err != nil {
+		return nil, err
+	}
+	return names.found, nil
+}
+
+// nameLookup collects the catalog resources that match the machine names or
+// patterns during a catalog scan
+type nameLookup struct {
+	opts      MatchOptions
+	matchers  map[string]nameMatcher
+	found     map[string][]CatalogResource
+	fullNames map[string]bool // the patterns found by their fully qualified name
+}
+
+// newNameLookup returns the lookup of the patterns, an invalid pattern is an error
+func newNameLookup(patterns []string, opts MatchOptions) (*nameLookup, error) {
+	l := &nameLookup{opts: opts, matchers: make(map[string]nameMatcher, len(patterns))}
+	for _, pattern := range patterns {
+		matcher, err := newNameMatcher(pattern, opts)
+		if err != nil {
+			return nil, err
+		}
+		l.matchers[pattern] = matcher
+	}
+	l.reset()
+	return l, nil
+}
+
+// exact tells whether the names are matched exactly
+func (l *nameLookup) exact() bool {
+	return l.opts.Mode == MatchExact || l.opts.Mode == ""
+}
+
+// reset forgets the resources found so far
+func (l *nameLookup) reset() {
+	l.found = make(map[string][]CatalogResource, len(l.matchers))
+	l.fullNames = make(map[string]bool)
+}
+
+// match adds the resource to the patterns it matches
+func (l *nameLookup) match(resource CatalogResource) {
+	for pattern, matcher := range l.matchers {
+		if !matcher(resource.Name) {
+			continue
+		}
+		if l.exact() && fullNameMatch(resource.Name, pattern, l.opts) {
+			if !l.fullNames[pattern] {
+				l.found[pattern] = nil
+			}
+			l.fullNames[pattern] = true
+		} else if l.fullNames[pattern] {
+			continue
+		}
+		l.found[pattern] = append(l.found[pattern], resource)
+	}
+}
+
+// done tells whether all names are found by their fully qualified name, no
+// other resource can match them
+func (l *nameLookup) done() bool {
+	return l.exact() && len(l.fullNames) == len(l.matchers)
+}
+
+// missing returns the names without a match, in the order of the patterns
+func (l *nameLookup) missing(patterns []string) []string {
+	var missing []string
+	for _, pattern := range patterns {
+		if len(l.found[pattern]) == 0 {
+			missing = append(missing, pattern)
+		}
+	}
+	return missing
+}
+
+// MachineLookup holds the virtual machines found by LookupVirtualMachines
+type MachineLookup struct {
+	Names       map[string][]CatalogResource   // the resources per machine name or pattern
+	Selectors   map[Selector][]CatalogResource // the resources per selector
+	Suggestions map[string][]string            // the closest names per exact name without a match
+}
+
+// LookupVirtualMachines looks up the machine names or patterns and the
+// selectors with a single catalog scan, like FindVirtualMachines and
+// FindVirtualMachinesBy. For an exact name without a match at most suggest
+// closest names are returned. The names to suggest are collected during the
+// scan, only when the scan was limited by a filter the catalog is scanned
+// again for the suggestions.
+func (c *Client) LookupVirtualMachines(ctx context.Context, patterns []string, opts MatchOptions, selectors []Selector, suggest int) (*MachineLookup, error) {
+	names, err := newNameLookup(patterns, opts)
+	if err != nil {
+		return nil, err
+	}
+	bySelector := newSelectorLookup(selectors)
+	candidates := newNameCandidates(opts.Prefix)
+
+	typeFilter := c.machineTypeFilter()
+	filter := typeFilter
+	nameConds, namesOK := nameConditions(patterns, opts)
+	idConds, idsOK := idConditions(selectors)
+	if namesOK && idsOK {
+		filter = anyOf(typeFilter, append(nameConds, idConds...))
+	}
+
+	var complete, stopped bool
+	match := func(resources []CatalogResource) bool {
+		for _, resource := range resources {
+			names.match(resource)
+			bySelector.match(resource)
+			candidates.add(resource)
+		}
+		stopped = names.done() && bySelector.done()
+		return stopped
+	}
+	reset := func(scanFilter string) {
+		names.reset()
+		bySelector.reset()
+		candidates.reset()
+		complete = scanFilter == "" || scanFilter == typeFilter
+	}
+	if err := c.scanVirtualMachines(ctx, filter, reset, match); err != nil {
+		return nil, err
+	}
+
+	lookup := &MachineLookup{Names: names.found, Selectors: bySelector.found}
+	if missing := names.missing(patterns); len(missing) > 0 && names.exact() && suggest > 0 {
+		if complete && !stopped {
+			lookup.Suggestions = candidates.closest(missing, suggest)
+		} else if lookup.Suggestions, err = c.SuggestVirtualMachineNames(ctx, missing, opts.Prefix, suggest); err != nil {
+			// The machines are found, only the suggestions are missing
+			c.tracef("Unable to suggest machine names: %s", err)
+		}
+	}
+	return lookup, nil
+}
+
+// scanVirtualMachines calls fn with the virtual machines of every catalog page
+// until fn returns true. vRA is asked to select the candidates with the
+// filter, the filter is only a pre-selection so fn has to match the
+// candidates exactly like on a full catalog scan. When the endpoint rejects
+// the filter, reset is called and the full catalog is scanned instead. Reset
+// is called with the filter of the scan that follows.
+func (c *Client) scanVirtualMachines(ctx context.Context, filter string, reset func(filter string), fn func([]CatalogResource) bool) error {
+	machines := func(resources []CatalogResource) bool {
+		var selected []CatalogResource
+		for _, resource := range resources {
+			if !c.isMachine(resource) {
+				continue
+			}
+			// Skip matches with only spaces as id (highly unlikely)
+			if strings.TrimSpace(resource.ID) == "" {
+				continue
+			}
+			selected = append(selected, resource)
+		}
+		return fn(selected)
+	}
+
+	reset(filter)
+	err := c.ScanCatalogResources(ctx, filter, machines)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest {
+		// The endpoint rejected the filter, fall back to scanning the full catalog
+		c.tracef("Catalog filter rejected (%s), scanning the full catalog", apiErr)
+		reset("")
+		err = c.ScanCatalogResources(ctx, "", machines)
+	}
+	return err
+}
+
+// machineFilter returns the OData filter that adds the virtual machine
+// candidates to the type filter. The names are only filtered on with exact
+// matching.
+func machineFilter(filter string, machines []string, opts MatchOptions) string {
+	if conditions, ok := nameConditions(machines, opts); ok {
+		return anyOf(filter, conditions)
+	}
+	return filter
+}
+
+// nameConditions returns the OData conditions that select the candidates of
+// the machine names, ok is false when the names can not be filtered on
+func nameConditions(machines []string, opts MatchOptions) (conditions []string, ok bool) {
+	if len(machines) == 0 {
+		return nil, true
+	}
+	if opts.Mode != MatchExact && opts.Mode != "" {
+		return nil, false
+	}
+
+	for _, machine := range machines {
+		name := "name"
+		if opts.IgnoreCase {
+			name = "tolower(name)"
+			machine = strings.ToLower(machine)
+		}
+		conditions = append(conditions, "endswith("+name+",'"+odataQuote(machine)+"')")
+	}
+	return conditions, true
+}
+
+// anyOf adds the conditions to the filter, a resource is selected by any of them
+func anyOf(filter string, conditions []string) string {
+	if len(conditions) == 0 {
+		return filter
+	}
+	return filter + " and (" + strings.Join(conditions, " or ") + ")"
+}
+
+// odataQuote escapes a value for use in a quoted OData string literal
+func odataQuote(value string) string {
+	return strings.Replace(value, "'", "''", -1)
+}
@@ -0,0 +1,208 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vra
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFindVirtualMachines(t *testing.T) {
+	resources := machines("ACMweb01", "XYZweb01", "ACMweb02", "ACMdb01")
+	resources = append(resources, CatalogResource{ID: "id-bp", Name: "ACMweb03", ResourceTypeRef: Ref{ID: "composition.resource.type.deployment"}})
+
+	tests := []struct {
+		name    string
+		pattern string
+		opts    MatchOptions
+		want    string
+	}{
+		{"exact name without prefix", "web02", MatchOptions{}, "[ACMweb02]"},
+		{"exact name is ambiguous", "web01", MatchOptions{}, "[ACMweb01 XYZweb01]"},
+		{"fully qualified name takes precedence", "XYZweb01", MatchOptions{}, "[XYZweb01]"},
+		{"ignore case", "WEB02", MatchOptions{IgnoreCase: true}, "[ACMweb02]"},
+		{"case differs", "WEB02", MatchOptions{}, "[]"},
+		{"not a machine", "web03", MatchOptions{}, "[]"},
+		{"glob", "web*", MatchOptions{Mode: MatchGlob}, "[ACMweb01 XYZweb01 ACMweb02]"},
+		{"regex", "(web|db)01", MatchOptions{Mode: MatchRegex}, "[ACMweb01 XYZweb01 ACMdb01]"},
+		{"glob on the fully qualified name", "ACMweb0*", MatchOptions{Mode: MatchGlob}, "[ACMweb01 ACMweb02]"},
+		{"regex on the fully qualified name", "xyz.*", MatchOptions{Mode: MatchRegex, IgnoreCase: true}, "[XYZweb01]"},
+		{"glob with a literal prefix", "XYZweb*", MatchOptions{Mode: MatchGlob, Prefix: TenantPrefix{literal: "XYZ"}}, "[XYZweb01]"},
+		{"literal prefix", "web01", MatchOptions{Prefix: TenantPrefix{literal: "XYZ"}}, "[XYZweb01]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newCatalogServer(t, resources, 2)
+			c := newTestClient(s.URL, 2, 2)
+
+			found, err := c.FindVirtualMachines(context.Background(), []string{tt.pattern}, tt.opts)
+			if err != nil {
+				t.Fatalf("FindVirtualMachines: %v", err)
+			}
+			if got := fmt.Sprint(names(found[tt.pattern])); got != tt.want {
+				t.Errorf("machines = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetVirtualMachineResourceIDFilterFallback(t *testing.T) {
+	s := newCatalogServer(t, machines("ACMweb01", "ACMweb02", "ACMdb01"), 1)
+	s.rejectFilter = true
+	c := newTestClient(s.URL, 1, 2)
+
+	id, err := c.GetVirtualMachineResourceID(context.Background(), "web02", MatchOptions{})
+	if err != nil || id != "id-2" {
+		t.Fatalf("GetVirtualMachineResourceID(web02) = %q, %v, want id-2", id, err)
+	}
+
+	// The filtered scan is rejected, the full catalog is scanned instead
+	s.mu.Lock()
+	filters := append([]string(nil), s.filters...)
+	s.mu.Unlock()
+	if len(filters) < 2 || !strings.Contains(filters[0], "endswith(name,'web02')") || filters[len(filters)-1] != "" {
+		t.Errorf("filters = %q, want a name filter and then the full catalog", filters)
+	}
+}
+
+func TestGetVirtualMachineResourceID(t *testing.T) {
+	s := newCatalogServer(t, machines("ACMweb01", "XYZweb01", "ACMweb02"), 10)
+	c := newTestClient(s.URL, 10, 1)
+	ctx := context.Background()
+
+	id, err := c.GetVirtualMachineResourceID(ctx, "web02", MatchOptions{})
+	if err != nil || id != "id-3" {
+		t.Errorf("GetVirtualMachineResourceID(web02) = %q, %v, want id-3", id, err)
+	}
+
+	_, err = c.GetVirtualMachineResourceID(ctx, "web01", MatchOptions{})
+	var ambiguous *AmbiguousMachineError
+	if !errors.As(err, &ambiguous) || !errors.Is(err, ErrAmbiguousMachine) || len(ambiguous.Candidates) != 2 {
+		t.Errorf("GetVirtualMachineResourceID(web01) = %v, want an *AmbiguousMachineError with 2 candidates", err)
+	}
+
+	_, err = c.GetVirtualMachineResourceID(ctx, "web03", MatchOptions{})
+	var notFound *MachineNotFoundError
+	if !errors.As(err, &notFound) || !errors.Is(err, ErrMachineNotFound) || !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetVirtualMachineResourceID(web03) = %v, want a *MachineNotFoundError", err)
+	}
+
+	// With the tenant prefix the name is unique
+	prefix, _ := ParseTenantPrefix("XYZ")
+	id, err = c.GetVirtualMachineResourceID(ctx, "web01", MatchOptions{Mode: MatchGlob, Prefix: prefix})
+	if err != nil || id != "id-2" {
+		t.Errorf("GetVirtualMachineResourceID(web01) with prefix XYZ = %q, %v, want id-2", id, err)
+	}
+}
+
+func TestMachinePlatform(t *testing.T) {
+	interfaceType := func(platform string) LiteralMap {
+		value, _ := json.Marshal(map[string]string{"type": "string", "value": platform})
+		return LiteralMap{Entries: []LiteralMapEntry{{Key: machineInterfaceTypeKey, Value: value}}}
+	}
+
+	tests := []struct {
+		name     string
+		resource CatalogResource
+		want     string
+	}{
+		{"resource data", CatalogResource{ResourceData: interfaceType("HyperV"), IconID: machineIconIDPrefix + "Virtual.vSphere"}, "HyperV"},
+		{"icon id", CatalogResource{IconID: machineIconIDPrefix + "Virtual.vSphere"}, "vSphere"},
+		{"unknown", CatalogResource{IconID: "cafe_default_icon_genericCatalogItem"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.resource.Platform(); got != tt.want {
+				t.Errorf("Platform() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	c := &Client{Platforms: []string{"vsphere"}}
+	vsphere := CatalogResource{ResourceTypeRef: Ref{ID: virtualMachineResourceType}, IconID: machineIconIDPrefix + "Virtual.vSphere"}
+	hyperV := CatalogResource{ResourceTypeRef: Ref{ID: virtualMachineResourceType}, ResourceData: interfaceType("HyperV")}
+	cloud := CatalogResource{ResourceTypeRef: Ref{ID: cloudMachineResourceType}, IconID: machineIconIDPrefix + "Cloud.vSphere"}
+	if !c.isMachine(vsphere) || c.isMachine(hyperV) || c.isMachine(cloud) {
+		t.Errorf("isMachine with platform vSphere: vSphere %v, HyperV %v, cloud %v", c.isMachine(vsphere), c.isMachine(hyperV), c.isMachine(cloud))
+	}
+	c.IncludeCloudMachines = true
+	if !c.isMachine(cloud) {
+		t.Errorf("isMachine(cloud) = false with IncludeCloudMachines")
+	}
+}
+
+func TestLookupVirtualMachines(t *testing.T) {
+	str := func(value string) string {
+		return `{"type": "string", "value": "` + value + `"}`
+	}
+	resources := machines("ACMweb01", "ACMweb02", "ACMdb01", "ACMdb02")
+	resources[2].ResourceData = literalMap("ip_address", str("10.0.0.3"))
+	resources[3].ResourceData = literalMap("app", str("crm"))
+
+	ip, _ := ParseSelector(SelectIP, "10.0.0.3")
+	property, _ := ParseSelector(SelectProperty, "app=crm")
+	id, _ := ParseSelector(SelectResourceID, "id-4")
+
+	tests := []struct {
+		name         string
+		patterns     []string
+		selectors    []Selector
+		rejectFilter bool
+		want         string
+		wantScans    int // scans of all pages
+	}{
+		{"names and selectors", []string{"web02"}, []Selector{ip}, false, "web02 [ACMweb02], ip 10.0.0.3 [ACMdb01], suggestions map[]", 1},
+		{"filtered scan needs a scan for the suggestions", []string{"web1"}, []Selector{id}, false, "web1 [], resource-id id-4 [ACMdb02], suggestions map[web1:[web01 web02]]", 2},
+		{"unfiltered scan", []string{"web1"}, []Selector{property}, false, "web1 [], property app=crm [ACMdb02], suggestions map[web1:[web01 web02]]", 1},
+		{"rejected filter", []string{"web1"}, []Selector{id}, true, "web1 [], resource-id id-4 [ACMdb02], suggestions map[web1:[web01 web02]]", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newCatalogServer(t, resources, 2)
+			s.rejectFilter = tt.rejectFilter
+			c := newTestClient(s.URL, 2, 1)
+
+			lookup, err := c.LookupVirtualMachines(context.Background(), tt.patterns, MatchOptions{}, tt.selectors, 3)
+			if err != nil {
+				t.Fatalf("LookupVirtualMachines: %v", err)
+			}
+			got := fmt.Sprintf("%s %v, %s %v, suggestions %v", tt.patterns[0], names(lookup.Names[tt.patterns[0]]), tt.selectors[0], names(lookup.Selectors[tt.selectors[0]]), lookup.Suggestions)
+			if got != tt.want {
+				t.Errorf("lookup = %s, want %s", got, tt.want)
+			}
+
+			// Every scan ends with the last page, a rejected filter is no scan
+			scans := 0
+			for _, page := range s.pages() {
+				if page == 2 {
+					scans++
+				}
+			}
+			if scans != tt.wantScans {
+				t.Errorf("%d catalog scans (pages %v), want %d", scans, s.pages(), tt.wantScans)
+			}
+		})
+	}
+}
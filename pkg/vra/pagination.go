@@ -0,0 +1,164 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vra
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// Default paging of the consumer catalog
+const (
+	DefaultPageSize    = 1000
+	DefaultPageWorkers = 4
+)
+
+// ScanCatalogResources reads all pages of the consumer catalog, optionally
+// filtered by an OData $filter expression, and calls fn
+// with the resources of every page, in page order. The pages are fetched
+// concurrently by at most Client.PageWorkers workers. When fn returns true
+// the scan stops and the remaining pages are not fetched.
+func (c *Client) ScanCatalogResources(ctx context.Context, filter string, fn func(resources []CatalogResource) bool) error {
+	pageSize := c.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	workers := c.PageWorkers
+	if workers <= 0 {
+		workers = DefaultPageWorkers
+	}
+
+	// The first page tells how many pages there are
+	first, err := c.GetCatalogResources(ctx, 1, pageSize, filter)
+	if err != nil {
+		return err
+	}
+	if fn(first.Content) {
+		return nil
+	}
+
+	totalPages := first.Metadata.TotalPages
+	if totalPages == 0 {
+		// No paging metadata, follow the "next" links instead
+		return c.followCatalogLinks(ctx, first, fn)
+	}
+	if totalPages == 1 {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		number int
+		page   *CatalogResourcePage
+		err    error
+	}
+	pages := make(chan int)
+	results := make(chan result)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers && i < totalPages-1; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for number := range pages {
+				page, err := c.GetCatalogResources(ctx, number, pageSize, filter)
+				select {
+				case results <- result{number: number, page: page, err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(pages)
+		for number := 2; number <= totalPages; number++ {
+			select {
+			case pages <- number:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Pages can arrive in any order, hand them to fn in page order so the
+	// outcome does not depend on which request completes first.
+	received := make(map[int]*CatalogResourcePage)
+	next := 2
+	for r := range results {
+		if r.err != nil {
+			return r.err
+		}
+		received[r.number] = r.page
+		for page, ok := received[next]; ok; page, ok = received[next] {
+			delete(received, next)
+			next++
+			if fn(page.Content) {
+				return nil
+			}
+		}
+	}
+	if next <= totalPages {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// followCatalogLinks reads the pages one by one by following the "next" links
+func (c *Client) followCatalogLinks(ctx context.Context, page *CatalogResourcePage, fn func(resources []CatalogResource) bool) error {
+	for {
+		href := nextLink(page.Links)
+		if href == "" {
+			return nil
+		}
+
+		req, err := c.newRequest(ctx, http.MethodGet, href, nil)
+		if err != nil {
+			return err
+		}
+		page = &CatalogResourcePage{}
+		if err := c.getJSON(req, page); err != nil {
+			return err
+		}
+		if fn(page.Content) {
+			return nil
+		}
+	}
+}
+
+// nextLink returns the href of the "next" link or an empty string on the last page
+func nextLink(links []Link) string {
+	for _, link := range links {
+		if link.Rel == "next" {
+			return link.Href
+		}
+	}
+	return ""
+}
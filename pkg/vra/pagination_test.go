@@ -0,0 +1,251 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vra
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestScanCatalogResourcesPageOrder(t *testing.T) {
+	var all []string
+	for i := 1; i <= 10; i++ {
+		all = append(all, fmt.Sprintf("vm%02d", i))
+	}
+	s := newCatalogServer(t, machines(all...), 1)
+	// The later pages are answered first
+	s.delay = func(page int) time.Duration { return time.Duration(10-page) * 3 * time.Millisecond }
+
+	c := newTestClient(s.URL, 1, 4)
+	var got []string
+	err := c.ScanCatalogResources(context.Background(), "", func(resources []CatalogResource) bool {
+		got = append(got, names(resources)...)
+		return false
+	})
+	if err != nil {
+		t.Fatalf("ScanCatalogResources: %v", err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(all) {
+		t.Errorf("resources = %v, want %v", got, all)
+	}
+	if len(s.pages()) != 10 {
+		t.Errorf("requested %d pages, want 10", len(s.pages()))
+	}
+}
+
+func TestScanCatalogResourcesStopsEarly(t *testing.T) {
+	var all []string
+	for i := 1; i <= 50; i++ {
+		all = append(all, fmt.Sprintf("vm%02d", i))
+	}
+	s := newCatalogServer(t, machines(all...), 1)
+	s.delay = func(page int) time.Duration { return time.Millisecond }
+	c := newTestClient(s.URL, 1, 2)
+
+	before := runtime.NumGoroutine()
+	var seen int
+	err := c.ScanCatalogResources(context.Background(), "", func(resources []CatalogResource) bool {
+		seen++
+		return seen == 3
+	})
+	if err != nil {
+		t.Fatalf("ScanCatalogResources: %v", err)
+	}
+	if seen != 3 {
+		t.Errorf("fn called %d times, want 3", seen)
+	}
+	// The workers may have started a few more pages, not the whole catalog
+	if pages := len(s.pages()); pages > 3+2+1 {
+		t.Errorf("requested %d pages after stopping at page 3", pages)
+	}
+
+	// All workers stop once the scan is done
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("%d goroutines left after the scan, %d before", n, before)
+	}
+}
+
+func TestScanCatalogResourcesError(t *testing.T) {
+	var all []string
+	for i := 1; i <= 8; i++ {
+		all = append(all, fmt.Sprintf("vm%02d", i))
+	}
+	s := newCatalogServer(t, machines(all...), 1)
+	s.fail = func(page int) int {
+		if page == 5 {
+			return http.StatusInternalServerError
+		}
+		return 0
+	}
+
+	c := newTestClient(s.URL, 1, 3)
+	err := c.ScanCatalogResources(context.Background(), "", func([]CatalogResource) bool { return false })
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("err = %v, want an *APIError with status 500", err)
+	}
+}
+
+func TestScanCatalogResourcesFollowsLinks(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		resp := CatalogResourcePage{Content: machines(fmt.Sprintf("vm%d", page))}
+		if page < 3 {
+			resp.Links = []Link{{Rel: "next", Href: fmt.Sprintf("%s/catalog-service/api/consumer/resources?page=%d", server.URL, page+1)}}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	c := newTestClient(server.URL, 1, 2)
+	var got []string
+	err := c.ScanCatalogResources(context.Background(), "", func(resources []CatalogResource) bool {
+		got = append(got, names(resources)...)
+		return false
+	})
+	if err != nil {
+		t.Fatalf("ScanCatalogResources: %v", err)
+	}
+	if want := "[vm1 vm2 vm3]"; fmt.Sprint(got) != want {
+		t.Errorf("resources = %v, want %s", got, want)
+	}
+}
+
+// catalogServer is a fake consumer catalog that serves the resources in
+// pages and records the requested pages
+type catalogServer struct {
+	*httptest.Server
+
+	resources    []CatalogResource
+	pageSize     int
+	rejectFilter bool                         // answer a $filter with 400 Bad Request
+	delay        func(page int) time.Duration // optional delay per page
+	fail         func(page int) int           // optional status code per page, 0 is OK
+	requested    []int                        // requested pages, in request order
+	filters      []string                     // $filter of every request
+	mu           sync.Mutex
+}
+
+// newCatalogServer starts a fake catalog with the resources, pageSize per page
+func newCatalogServer(t *testing.T, resources []CatalogResource, pageSize int) *catalogServer {
+	t.Helper()
+	s := &catalogServer{resources: resources, pageSize: pageSize}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *catalogServer) serve(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/catalog-service/api/consumer/resources" {
+		http.NotFound(w, r)
+		return
+	}
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	filter := query.Get("$filter")
+
+	s.mu.Lock()
+	s.requested = append(s.requested, page)
+	s.filters = append(s.filters, filter)
+	s.mu.Unlock()
+
+	if s.delay != nil {
+		time.Sleep(s.delay(page))
+	}
+	if filter != "" && s.rejectFilter {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"errors":[{"code":10101,"message":"Invalid filter"}]}`)
+		return
+	}
+	if s.fail != nil {
+		if status := s.fail(page); status != 0 {
+			w.WriteHeader(status)
+			return
+		}
+	}
+
+	totalPages := (len(s.resources) + s.pageSize - 1) / s.pageSize
+	first := (page - 1) * s.pageSize
+	last := first + s.pageSize
+	if first > len(s.resources) {
+		first = len(s.resources)
+	}
+	if last > len(s.resources) {
+		last = len(s.resources)
+	}
+	json.NewEncoder(w).Encode(CatalogResourcePage{
+		Content:  s.resources[first:last],
+		Metadata: PageMetadata{Size: s.pageSize, TotalElements: len(s.resources), TotalPages: totalPages, Number: page},
+	})
+}
+
+// pages returns the requested pages
+func (s *catalogServer) pages() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int(nil), s.requested...)
+}
+
+// machines returns virtual machine resources with the names
+func machines(names ...string) []CatalogResource {
+	resources := make([]CatalogResource, len(names))
+	for i, name := range names {
+		resources[i] = CatalogResource{
+			ID:              fmt.Sprintf("id-%d", i+1),
+			Name:            name,
+			ResourceTypeRef: Ref{ID: virtualMachineResourceType},
+			IconID:          "Infrastructure.CatalogItem.Machine.Virtual.vSphere",
+		}
+	}
+	return resources
+}
+
+// newTestClient returns a client of the server without keep-alive
+// connections, so no connection goroutines are left after a test
+func newTestClient(baseURL string, pageSize, workers int) *Client {
+	c := NewClient(baseURL, "tenant")
+	c.HTTPClient = &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+	c.PageSize = pageSize
+	c.PageWorkers = workers
+	return c
+}
+
+func names(resources []CatalogResource) []string {
+	var names []string
+	for _, resource := range resources {
+		names = append(names, resource.Name)
+	}
+	return names
+}
@@ -0,0 +1,101 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vra
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TenantPrefix is the tenant specific prefix in front of the virtual machine
+// names in vRA. The zero value is the default prefix of any three characters.
+type TenantPrefix struct {
+	none    bool
+	literal string
+	re      *regexp.Regexp
+}
+
+// defaultTenantPrefix matches any three characters
+var defaultTenantPrefix = regexp.MustCompile(`^(?:.{3})$`)
+
+// ParseTenantPrefix returns the tenant prefix for "none" (no prefix), a
+// regular expression written as "regex:<expression>" or a literal prefix.
+// An empty string is the default prefix of any three characters.
+func ParseTenantPrefix(prefix string) (TenantPrefix, error) {
+	switch {
+	case prefix == "":
+		return TenantPrefix{}, nil
+	case strings.EqualFold(prefix, "none"):
+		return TenantPrefix{none: true}, nil
+	case strings.HasPrefix(prefix, "regex:"):
+		expr := strings.TrimPrefix(prefix, "regex:")
+		re, err := regexp.Compile(`^(?:` + expr + `)$`)
+		if err != nil {
+			return TenantPrefix{}, fmt.Errorf("vra: invalid tenant prefix regex %q: %w", expr, err)
+		}
+		return TenantPrefix{re: re}, nil
+	}
+	return TenantPrefix{literal: prefix}, nil
+}
+
+// String returns the prefix like it is parsed by ParseTenantPrefix
+func (p TenantPrefix) String() string {
+	switch {
+	case p.none:
+		return "none"
+	case p.literal != "":
+		return p.literal
+	case p.re != nil:
+		return "regex:" + strings.TrimSuffix(strings.TrimPrefix(p.re.String(), "^(?:"), ")$")
+	}
+	return "regex:.{3}"
+}
+
+// remainders returns the possible machine names after removing the prefix
+// from the resource name. A regular expression can match prefixes of
+// different lengths, so there can be more than one.
+func (p TenantPrefix) remainders(name string, ignoreCase bool) []string {
+	switch {
+	case p.none:
+		return []string{name}
+	case p.literal != "":
+		if len(name) < len(p.literal) {
+			return nil
+		}
+		if head := name[:len(p.literal)]; head == p.literal || (ignoreCase && strings.EqualFold(head, p.literal)) {
+			return []string{name[len(p.literal):]}
+		}
+		return nil
+	}
+
+	re := p.re
+	if re == nil {
+		re = defaultTenantPrefix
+	}
+	var remainders []string
+	for i := 0; i <= len(name); i++ {
+		if re.MatchString(name[:i]) {
+			remainders = append(remainders, name[i:])
+		}
+	}
+	return remainders
+}
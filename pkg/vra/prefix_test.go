@@ -0,0 +1,80 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vra
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseTenantPrefix(t *testing.T) {
+	tests := []struct {
+		prefix  string
+		want    string
+		wantErr bool
+	}{
+		{"", "regex:.{3}", false},
+		{"none", "none", false},
+		{"NONE", "none", false},
+		{"ACME-", "ACME-", false},
+		{"regex:[A-Z]{2,4}", "regex:[A-Z]{2,4}", false},
+		{"regex:[A-Z", "", true},
+	}
+	for _, tt := range tests {
+		prefix, err := ParseTenantPrefix(tt.prefix)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTenantPrefix(%q) error = %v, want error %v", tt.prefix, err, tt.wantErr)
+			continue
+		}
+		if err == nil && prefix.String() != tt.want {
+			t.Errorf("ParseTenantPrefix(%q) = %q, want %q", tt.prefix, prefix.String(), tt.want)
+		}
+	}
+}
+
+func TestTenantPrefixRemainders(t *testing.T) {
+	tests := []struct {
+		prefix     string
+		name       string
+		ignoreCase bool
+		want       string
+	}{
+		{"", "ACMweb01", false, "[web01]"},
+		{"", "AC", false, "[]"},
+		{"none", "ACMweb01", false, "[ACMweb01]"},
+		{"ACME-", "ACME-web01", false, "[web01]"},
+		{"ACME-", "acme-web01", false, "[]"},
+		{"ACME-", "acme-web01", true, "[web01]"},
+		{"ACME-", "ACM", false, "[]"},
+		{"regex:[A-Z]{2,4}", "ACMEweb01", false, "[MEweb01 Eweb01 web01]"},
+		{"regex:[A-Z]{2,4}-", "ACME-web01", false, "[web01]"},
+		{"regex:[A-Z]{2,4}-", "web01", false, "[]"},
+	}
+	for _, tt := range tests {
+		prefix, err := ParseTenantPrefix(tt.prefix)
+		if err != nil {
+			t.Fatalf("ParseTenantPrefix(%q): %v", tt.prefix, err)
+		}
+		if got := fmt.Sprint(prefix.remainders(tt.name, tt.ignoreCase)); got != tt.want {
+			t.Errorf("%q.remainders(%q, %v) = %s, want %s", tt.prefix, tt.name, tt.ignoreCase, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vra
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Request states reported by vRA, the stateName of a request
+const (
+	RequestStateSuccessful = "Successful"
+	RequestStateFailed     = "Failed"
+)
+
+// Request states of the vRA request state machine, the state of a request
+const (
+	StateUnsubmitted         = "UNSUBMITTED"
+	StateSubmitted           = "SUBMITTED"
+	StatePendingPreApproval  = "PENDING_PRE_APPROVAL"
+	StatePreApproved         = "PRE_APPROVED"
+	StatePreRejected         = "PRE_REJECTED"
+	StateInProgress          = "IN_PROGRESS"
+	StatePendingPostApproval = "PENDING_POST_APPROVAL"
+	StatePostApproved        = "POST_APPROVED"
+	StatePostRejected        = "POST_REJECTED"
+	StateRejected            = "REJECTED"
+	StateProviderCompleted   = "PROVIDER_COMPLETED"
+	StateProviderFailed      = "PROVIDER_FAILED"
+	StateSuccessful          = "SUCCESSFUL"
+	StatePartiallySuccessful = "PARTIALLY_SUCCESSFUL"
+	StateFailed              = "FAILED"
+	StateCancelled           = "CANCELLED"
+)
+
+// Errors of a request that did not succeed
+var (
+	ErrRequestFailed    = errors.New("vra: request failed")
+	ErrRequestRejected  = errors.New("vra: request rejected")
+	ErrRequestCancelled = errors.New("vra: request cancelled")
+)
+
+// Errors of a request that did not finish in time, they are also a context.DeadlineExceeded
+var (
+	ErrRequestTimeout  error = timeoutError("vra: request not finished in time")
+	ErrApprovalTimeout error = timeoutError("vra: request not approved in time")
+)
+
+// timeoutError is a timeout of WaitForRequest
+type timeoutError string
+
+func (e timeoutError) Error() string {
+	return string(e)
+}
+
+func (e timeoutError) Is(target error) bool {
+	return target == context.DeadlineExceeded
+}
+
+// requestState returns the state of the request, for a response without
+// state it is derived from the state name
+func requestState(request *ResourceActionRequest) string {
+	if request.State != "" {
+		return request.State
+	}
+	switch request.StateName {
+	case RequestStateSuccessful:
+		return StateSuccessful
+	case RequestStateFailed:
+		return StateFailed
+	}
+	return StateInProgress
+}
+
+// pendingApproval tells whether the request is waiting for an approval
+func pendingApproval(state string) bool {
+	return state == StatePendingPreApproval || state == StatePendingPostApproval
+}
+
+// RequestFailedError holds the status of a request that did not succeed. It
+// is an ErrRequestRejected or ErrRequestCancelled when the request was
+// rejected or cancelled, otherwise an ErrRequestFailed.
+type RequestFailedError struct {
+	Request *ResourceActionRequest
+}
+
+// Error returns the reason of the failure as reported by vRA, with the
+// request number and id to look the request up
+func (e *RequestFailedError) Error() string {
+	request := e.Request
+	if request == nil {
+		return ErrRequestFailed.Error()
+	}
+
+	msg := fmt.Sprintf("%s: request %d (%s), state %s", e.Unwrap(), request.RequestNumber, request.ID, request.StateName)
+	if request.Phase != "" {
+		msg += ", phase " + request.Phase
+	}
+	if details := strings.TrimSpace(request.RequestCompletion.CompletionDetails); details != "" {
+		msg += ": " + details
+	}
+	if reasons := strings.TrimSpace(request.Reasons); reasons != "" {
+		msg += " (" + reasons + ")"
+	}
+	return msg
+}
+
+// Unwrap returns the class of the failure
+func (e *RequestFailedError) Unwrap() error {
+	if e.Request == nil {
+		return ErrRequestFailed
+	}
+	switch requestState(e.Request) {
+	case StateRejected, StatePreRejected, StatePostRejected:
+		return ErrRequestRejected
+	case StateCancelled:
+		return ErrRequestCancelled
+	}
+	return ErrRequestFailed
+}
+
+// PollOptions controls how often WaitForRequest reads the request state.
+// The first poll is done after Interval, every next interval is Multiplier
+// times longer up to MaxInterval. Jitter randomizes every interval by the
+// given fraction, e.g. 0.1 for +/- 10%. A Retry-After header sent by vRA
+// takes precedence over the computed interval.
+//
+// MaxWait limits the time the request is processed and ApprovalTimeout the
+// time the request waits for approval, zero is no limit. The time waiting
+// for approval does not count for MaxWait.
+type PollOptions struct {
+	Interval        time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	Jitter          float64
+	MaxWait         time.Duration
+	ApprovalTimeout time.Duration
+}
+
+// DefaultPollOptions polls every 10 seconds
+var DefaultPollOptions = PollOptions{
+	Interval:    10 * time.Second,
+	MaxInterval: time.Minute,
+	Multiplier:  1,
+	Jitter:      0,
+}
+
+// next returns the interval following the current interval
+func (o PollOptions) next(current time.Duration) time.Duration {
+	next := current
+	if o.Multiplier > 1 {
+		next = time.Duration(float64(current) * o.Multiplier)
+	}
+	if o.MaxInterval > 0 && next > o.MaxInterval {
+		next = o.MaxInterval
+	}
+	return next
+}
+
+// jitter randomizes the interval by the jitter fraction
+func (o PollOptions) jitter(interval time.Duration) time.Duration {
+	if o.Jitter <= 0 {
+		return interval
+	}
+	delta := (rand.Float64()*2 - 1) * o.Jitter * float64(interval)
+	return interval + time.Duration(delta)
+}
+
+// GetRequest returns the current status of the request
+// (GET {requestStatusURL})
+func (c *Client) GetRequest(ctx context.Context, requestStatusURL string) (*ResourceActionRequest, error) {
+	request, _, err := c.getRequest(ctx, requestStatusURL)
+	return request, err
+}
+
+// getRequest returns the current status of the request and the delay
+// requested by vRA in the Retry-After header, if any.
+func (c *Client) getRequest(ctx context.Context, requestStatusURL string) (*ResourceActionRequest, time.Duration, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, requestStatusURL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resp, respBody, err := c.do(req, http.StatusOK)
+	var retryAfter time.Duration
+	if resp != nil {
+		retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+	}
+	if err != nil {
+		return nil, retryAfter, err
+	}
+
+	var request ResourceActionRequest
+	if err := json.Unmarshal(respBody, &request); err != nil {
+		return nil, retryAfter, err
+	}
+	if request.State == "" && request.StateName == "" {
+		return nil, retryAfter, errors.New("vra: no request state in response")
+	}
+	return &request, retryAfter, nil
+}
+
+// parseRetryAfter returns the delay of a Retry-After header value, in seconds or as HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
+// WaitForRequest polls the request state until the request is successful,
+// has failed, is rejected or cancelled, or the context is done. The optional
+// progress function is called with every request status that is read. A
+// deadline on the context limits the total waiting time, including the
+// time waiting for approval.
+func (c *Client) WaitForRequest(ctx context.Context, requestStatusURL string, opts PollOptions, progress func(request *ResourceActionRequest)) error {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultPollOptions.Interval
+	}
+
+	// The time waiting for approval is tracked separately
+	start := time.Now()
+	var approvalStart time.Time
+	var approvalTotal time.Duration
+	remaining := func(now time.Time) time.Duration {
+		if !approvalStart.IsZero() {
+			if opts.ApprovalTimeout <= 0 {
+				return 0
+			}
+			return opts.ApprovalTimeout - now.Sub(approvalStart)
+		}
+		if opts.MaxWait <= 0 {
+			return 0
+		}
+		return opts.MaxWait - (now.Sub(start) - approvalTotal)
+	}
+
+	// timeout returns the error when the time of the current phase is up
+	stateName := ""
+	timeout := func(now time.Time) error {
+		if remaining(now) > 0 {
+			return nil
+		}
+		switch {
+		case !approvalStart.IsZero() && opts.ApprovalTimeout > 0:
+			return ErrApprovalTimeout
+		case approvalStart.IsZero() && opts.MaxWait > 0 && stateName != "":
+			return fmt.Errorf("%w, state %s", ErrRequestTimeout, stateName)
+		case approvalStart.IsZero() && opts.MaxWait > 0:
+			return ErrRequestTimeout
+		}
+		return nil
+	}
+
+	interval := opts.Interval
+	delay := opts.jitter(interval)
+	for {
+		// Do not sleep past the timeout of the current phase
+		if left := remaining(time.Now()); left > 0 && left < delay {
+			delay = left
+		}
+
+		// Give the system some time before polling the request status
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		request, retryAfter, err := c.getRequest(ctx, requestStatusURL)
+		interval = opts.next(interval)
+		delay = opts.jitter(interval)
+		if retryAfter > 0 {
+			delay = retryAfter
+		}
+
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode == http.StatusServiceUnavailable) {
+			// vRA is busy, try again later
+			c.tracef("Request status unavailable (%s), retrying in %s", apiErr, delay)
+			if err := timeout(time.Now()); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(request)
+		}
+
+		now := time.Now()
+		state := requestState(request)
+		stateName = request.StateName
+		switch state {
+		case StateSuccessful:
+			return nil
+		case StateFailed, StateProviderFailed, StatePartiallySuccessful,
+			StateRejected, StatePreRejected, StatePostRejected, StateCancelled:
+			return &RequestFailedError{Request: request}
+		}
+
+		switch {
+		case pendingApproval(state) && approvalStart.IsZero():
+			approvalStart = now
+		case !pendingApproval(state) && !approvalStart.IsZero():
+			approvalTotal += now.Sub(approvalStart)
+			approvalStart = time.Time{}
+		}
+
+		if err := timeout(now); err != nil {
+			return err
+		}
+	}
+}
+
+// CancelRequest asks vRA to cancel the request, e.g. when the caller stops
+// waiting for it
+func (c *Client) CancelRequest(ctx context.Context, requestStatusURL string) error {
+	req, err := c.newRequest(ctx, http.MethodPost, strings.TrimSuffix(requestStatusURL, "/")+"/cancel", nil)
+	if err != nil {
+		return err
+	}
+
+	// vRA answers with the cancelled request or with an empty response
+	_, _, err = c.do(req, http.StatusOK, http.StatusAccepted, http.StatusNoContent)
+	return err
+}
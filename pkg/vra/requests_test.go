@@ -0,0 +1,276 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vra
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWaitForRequestRetryAfter(t *testing.T) {
+	var mu sync.Mutex
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		polls++
+		if polls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		json.NewEncoder(w).Encode(ResourceActionRequest{StateName: RequestStateSuccessful})
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, "tenant")
+	if err := c.WaitForRequest(context.Background(), server.URL+"/request-id", testPollOptions, nil); err != nil {
+		t.Errorf("WaitForRequest = %v, want nil after a 429", err)
+	}
+}
+
+// testPollOptions polls quickly without backoff or jitter
+var testPollOptions = PollOptions{Interval: 5 * time.Millisecond, Multiplier: 1}
+
+// stateNames are the state names of the fake request
+var stateNames = map[string]string{
+	StatePendingPreApproval:  "Pending Approval",
+	StatePendingPostApproval: "Pending Approval",
+	StateInProgress:          "In Progress",
+	StateSuccessful:          "Successful",
+	StateFailed:              "Failed",
+	StateProviderFailed:      "Provider Failed",
+	StateRejected:            "Rejected",
+	StateCancelled:           "Cancelled",
+}
+
+// newRequestServer serves a request in the state returned by state for the
+// time since the server started
+func newRequestServer(t *testing.T, state func(elapsed time.Duration) string) *httptest.Server {
+	t.Helper()
+	start := time.Now()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s := state(time.Since(start))
+		json.NewEncoder(w).Encode(ResourceActionRequest{
+			ID:                "request-id",
+			RequestNumber:     42,
+			State:             s,
+			StateName:         stateNames[s],
+			RequestCompletion: RequestCompletion{CompletionDetails: "details of " + s},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// sequence returns the states one by one per poll, the last one is repeated
+func sequence(states ...string) func(time.Duration) string {
+	var mu sync.Mutex
+	return func(time.Duration) string {
+		mu.Lock()
+		defer mu.Unlock()
+		state := states[0]
+		if len(states) > 1 {
+			states = states[1:]
+		}
+		return state
+	}
+}
+
+func TestRequestFailedError(t *testing.T) {
+	err := &RequestFailedError{Request: &ResourceActionRequest{
+		ID:                "request-id",
+		RequestNumber:     42,
+		State:             StateFailed,
+		StateName:         "Failed",
+		Phase:             "FAILED",
+		Reasons:           "by policy",
+		RequestCompletion: RequestCompletion{CompletionDetails: "datastore ds01 is full"},
+	}}
+	want := "vra: request failed: request 42 (request-id), state Failed, phase FAILED: datastore ds01 is full (by policy)"
+	if err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err.Error(), want)
+	}
+	if got := (&RequestFailedError{}).Error(); got != ErrRequestFailed.Error() {
+		t.Errorf("Error() without request = %q", got)
+	}
+}
+
+func TestWaitForRequestStateName(t *testing.T) {
+	// An older response without the state field
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"id": "request-id", "stateName": RequestStateFailed})
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, "tenant")
+	err := c.WaitForRequest(context.Background(), server.URL+"/request-id", testPollOptions, nil)
+	if !errors.Is(err, ErrRequestFailed) {
+		t.Errorf("WaitForRequest = %v, want ErrRequestFailed", err)
+	}
+}
+
+func TestWaitForRequestStates(t *testing.T) {
+	tests := []struct {
+		name   string
+		states []string
+		want   error
+	}{
+		{"successful", []string{StateSubmitted, StateInProgress, StateSuccessful}, nil},
+		{"approved", []string{StatePendingPreApproval, StatePreApproved, StateInProgress, StatePendingPostApproval, StatePostApproved, StateSuccessful}, nil},
+		{"failed", []string{StateInProgress, StateFailed}, ErrRequestFailed},
+		{"provider failed", []string{StateInProgress, StateProviderCompleted, StateProviderFailed}, ErrRequestFailed},
+		{"partially successful", []string{StatePartiallySuccessful}, ErrRequestFailed},
+		{"rejected", []string{StatePendingPreApproval, StateRejected}, ErrRequestRejected},
+		{"pre-rejected", []string{StatePendingPreApproval, StatePreRejected}, ErrRequestRejected},
+		{"post-rejected", []string{StatePendingPostApproval, StatePostRejected}, ErrRequestRejected},
+		{"cancelled", []string{StateInProgress, StateCancelled}, ErrRequestCancelled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newRequestServer(t, sequence(tt.states...))
+			c := NewClient(server.URL, "tenant")
+
+			var polled []string
+			err := c.WaitForRequest(context.Background(), server.URL+"/request-id", testPollOptions, func(request *ResourceActionRequest) {
+				polled = append(polled, request.State)
+			})
+			if !errors.Is(err, tt.want) || (tt.want == nil) != (err == nil) {
+				t.Fatalf("WaitForRequest = %v, want %v", err, tt.want)
+			}
+			if len(polled) != len(tt.states) {
+				t.Errorf("polled states %v, want %v", polled, tt.states)
+			}
+
+			var failed *RequestFailedError
+			if tt.want != nil && (!errors.As(err, &failed) || failed.Request.RequestNumber != 42) {
+				t.Errorf("err = %v, want a *RequestFailedError with the request", err)
+			}
+		})
+	}
+}
+
+func TestWaitForRequestTimeouts(t *testing.T) {
+	approval := func(d time.Duration) func(time.Duration) string {
+		return func(elapsed time.Duration) string {
+			switch {
+			case elapsed < d:
+				return StatePendingPreApproval
+			case elapsed < d+30*time.Millisecond:
+				return StateInProgress
+			}
+			return StateSuccessful
+		}
+	}
+
+	tests := []struct {
+		name  string
+		state func(time.Duration) string
+		opts  PollOptions
+		want  error
+	}{
+		{"max wait", sequence(StateInProgress), PollOptions{MaxWait: 50 * time.Millisecond}, ErrRequestTimeout},
+		{"approval timeout", sequence(StatePendingPreApproval), PollOptions{ApprovalTimeout: 50 * time.Millisecond}, ErrApprovalTimeout},
+		{"post-approval timeout", sequence(StateInProgress, StatePendingPostApproval), PollOptions{MaxWait: time.Second, ApprovalTimeout: 50 * time.Millisecond}, ErrApprovalTimeout},
+		{"approval does not count for max wait", approval(150 * time.Millisecond), PollOptions{MaxWait: 100 * time.Millisecond}, nil},
+		{"approval without a timeout", approval(100 * time.Millisecond), PollOptions{MaxWait: time.Second}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newRequestServer(t, tt.state)
+			c := NewClient(server.URL, "tenant")
+			opts := tt.opts
+			opts.Interval, opts.Multiplier = testPollOptions.Interval, testPollOptions.Multiplier
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			err := c.WaitForRequest(ctx, server.URL+"/request-id", opts, nil)
+			if !errors.Is(err, tt.want) || (tt.want == nil) != (err == nil) {
+				t.Fatalf("WaitForRequest = %v, want %v", err, tt.want)
+			}
+			if tt.want != nil && (!errors.Is(err, context.DeadlineExceeded) || ctx.Err() != nil) {
+				t.Errorf("WaitForRequest = %v, want a context.DeadlineExceeded before the test deadline", err)
+			}
+		})
+	}
+}
+
+func TestCancelRequest(t *testing.T) {
+	tests := []struct {
+		status  int
+		wantErr bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusAccepted, false},
+		{http.StatusNoContent, false},
+		{http.StatusNotFound, true},
+		{http.StatusBadRequest, true},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			var method, path string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				method, path = r.Method, r.URL.Path
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			c := NewClient(server.URL, "tenant")
+			err := c.CancelRequest(context.Background(), server.URL+"/catalog-service/api/consumer/requests/request-id")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CancelRequest = %v, want error %v", err, tt.wantErr)
+			}
+			var apiErr *APIError
+			if tt.wantErr && (!errors.As(err, &apiErr) || apiErr.StatusCode != tt.status) {
+				t.Errorf("CancelRequest = %v, want an *APIError with status %d", err, tt.status)
+			}
+			if method != http.MethodPost || !strings.HasSuffix(path, "/requests/request-id/cancel") {
+				t.Errorf("request %s %s, want POST .../requests/request-id/cancel", method, path)
+			}
+		})
+	}
+}
+
+func TestWaitForRequestBusy(t *testing.T) {
+	// vRA keeps answering busy, the timeout still applies
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := NewClient(server.URL, "tenant")
+	opts := testPollOptions
+	opts.MaxWait = 100 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := c.WaitForRequest(ctx, server.URL+"/request-id", opts, nil)
+	if !errors.Is(err, ErrRequestTimeout) || ctx.Err() != nil {
+		t.Errorf("WaitForRequest = %v, want ErrRequestTimeout before the test deadline", err)
+	}
+}
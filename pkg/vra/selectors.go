@@ -0,0 +1,212 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vra
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// SelectorKind is the machine attribute that is compared by a Selector
+type SelectorKind string
+
+// Selector kinds
+const (
+	SelectResourceID SelectorKind = "resource-id" // the catalog resource id
+	SelectIP         SelectorKind = "ip"          // an IP address of the machine
+	SelectHostname   SelectorKind = "hostname"    // the host name or FQDN of the machine
+	SelectProperty   SelectorKind = "property"    // a resource data or custom property, "key=value"
+)
+
+// Resource data keys of a virtual machine
+const (
+	machineNameKey    = "MachineName"
+	ipAddressKey      = "ip_address"
+	networkListKey    = "NETWORK_LIST"
+	networkAddressKey = "NETWORK_ADDRESS"
+)
+
+// Selector selects virtual machines by another attribute than their name.
+// The attributes are read from the resource data of the catalog resource.
+type Selector struct {
+	Kind  SelectorKind
+	Key   string // the property key, only for SelectProperty
+	Value string
+}
+
+// ParseSelector returns the selector of the kind for the value, a property
+// is written as "key=value"
+func ParseSelector(kind SelectorKind, value string) (Selector, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return Selector{}, fmt.Errorf("vra: empty %s selector", kind)
+	}
+
+	switch kind {
+	case SelectResourceID, SelectHostname:
+		return Selector{Kind: kind, Value: value}, nil
+	case SelectIP:
+		ip := net.ParseIP(value)
+		if ip == nil {
+			return Selector{}, fmt.Errorf("vra: invalid IP address %q", value)
+		}
+		return Selector{Kind: kind, Value: ip.String()}, nil
+	case SelectProperty:
+		parts := strings.SplitN(value, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return Selector{}, fmt.Errorf("vra: invalid property %q, use key=value", value)
+		}
+		return Selector{Kind: kind, Key: strings.TrimSpace(parts[0]), Value: parts[1]}, nil
+	}
+	return Selector{}, fmt.Errorf("vra: unknown selector %q", kind)
+}
+
+func (s Selector) String() string {
+	if s.Kind == SelectProperty {
+		return string(s.Kind) + " " + s.Key + "=" + s.Value
+	}
+	return string(s.Kind) + " " + s.Value
+}
+
+// Unique tells whether the selector is meant to select a single machine. A
+// property like an application id can select a group of machines.
+func (s Selector) Unique() bool {
+	return s.Kind != SelectProperty
+}
+
+// matches tells whether the virtual machine is selected
+func (s Selector) matches(resource CatalogResource) bool {
+	data := resource.ResourceData
+
+	switch s.Kind {
+	case SelectResourceID:
+		return strings.EqualFold(resource.ID, s.Value)
+
+	case SelectIP:
+		addresses := []string{data.String(ipAddressKey)}
+		for _, network := range data.Complex(networkListKey) {
+			addresses = append(addresses, network.String(networkAddressKey))
+		}
+		ip := net.ParseIP(s.Value)
+		for _, address := range addresses {
+			if other := net.ParseIP(strings.TrimSpace(address)); other != nil && other.Equal(ip) {
+				return true
+			}
+		}
+		return false
+
+	case SelectHostname:
+		// A FQDN also matches the host name without the domain
+		host := strings.TrimSuffix(s.Value, ".")
+		short := strings.SplitN(host, ".", 2)[0]
+		for _, name := range []string{data.String(machineNameKey), resource.Name} {
+			if name != "" && (strings.EqualFold(name, host) || strings.EqualFold(name, short)) {
+				return true
+			}
+		}
+		return false
+
+	case SelectProperty:
+		return data.String(s.Key) == s.Value
+	}
+	return false
+}
+
+// FindVirtualMachinesBy looks up several selectors with a single catalog scan
+// and returns the matching catalog resources per selector, in catalog order.
+// Selectors without a match are missing from the result.
+func (c *Client) FindVirtualMachinesBy(ctx context.Context, selectors []Selector) (map[Selector][]CatalogResource, error) {
+	lookup := newSelectorLookup(selectors)
+	match := func(resources []CatalogResource) bool {
+		for _, resource := range resources {
+			lookup.match(resource)
+		}
+		return lookup.done()
+	}
+	reset := func(string) {
+		lookup.reset()
+	}
+	if err := c.scanVirtualMachines(ctx, selectorFilter(c.machineTypeFilter(), selectors), reset, match); err != nil {
+		return nil, err
+	}
+	return lookup.found, nil
+}
+
+// selectorLookup collects the catalog resources that match the selectors
+// during a catalog scan
+type selectorLookup struct {
+	selectors []Selector
+	byID      bool // all selectors are resource ids
+	found     map[Selector][]CatalogResource
+}
+
+func newSelectorLookup(selectors []Selector) *selectorLookup {
+	l := &selectorLookup{selectors: selectors, byID: true}
+	for _, selector := range selectors {
+		l.byID = l.byID && selector.Kind == SelectResourceID
+	}
+	l.reset()
+	return l
+}
+
+// reset forgets the resources found so far
+func (l *selectorLookup) reset() {
+	l.found = make(map[Selector][]CatalogResource, len(l.selectors))
+}
+
+// match adds the resource to the selectors that select it
+func (l *selectorLookup) match(resource CatalogResource) {
+	for _, selector := range l.selectors {
+		if selector.matches(resource) {
+			l.found[selector] = append(l.found[selector], resource)
+		}
+	}
+}
+
+// done tells whether all selectors are found. Resource ids are unique, the
+// scan stops once all of them are found.
+func (l *selectorLookup) done() bool {
+	return l.byID && len(l.found) == len(l.selectors)
+}
+
+// selectorFilter returns the OData filter that adds the virtual machine
+// candidates to the type filter. Only resource ids are filtered on, the
+// resource data can not be filtered by vRA.
+func selectorFilter(filter string, selectors []Selector) string {
+	if conditions, ok := idConditions(selectors); ok {
+		return anyOf(filter, conditions)
+	}
+	return filter
+}
+
+// idConditions returns the OData conditions that select the resource ids of
+// the selectors, ok is false when a selector can not be filtered on
+func idConditions(selectors []Selector) (conditions []string, ok bool) {
+	for _, selector := range selectors {
+		if selector.Kind != SelectResourceID {
+			return nil, false
+		}
+		conditions = append(conditions, "id eq '"+odataQuote(selector.Value)+"'")
+	}
+	return conditions, true
+}
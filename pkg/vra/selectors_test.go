@@ -0,0 +1,126 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vra
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestFindVirtualMachinesBy(t *testing.T) {
+	network := func(address string) string {
+		return `{"type": "multiple", "items": [{"type": "complex", "values": {"entries": [
+			{"key": "NETWORK_ADDRESS", "value": {"type": "string", "value": "` + address + `"}}
+		]}}]}`
+	}
+	str := func(value string) string {
+		return `{"type": "string", "value": "` + value + `"}`
+	}
+
+	resources := machines("ACMweb01", "ACMweb02", "ACMweb03", "ACMdb01")
+	resources[0].ResourceData = literalMap("ip_address", str("10.0.0.1"), "app", str("shop"))
+	resources[1].ResourceData = literalMap("ip_address", str(""), "NETWORK_LIST", network("10.0.0.2"), "app", str("shop"))
+	resources[2].ResourceData = literalMap("MachineName", str("web03"))
+	resources[3].ResourceData = literalMap("MachineName", str("ACMdb01"), "app", str("crm"))
+
+	tests := []struct {
+		kind  SelectorKind
+		value string
+		want  string
+	}{
+		{SelectIP, "10.0.0.1", "[ACMweb01]"},
+		{SelectIP, "10.0.0.2", "[ACMweb02]"},
+		{SelectIP, "10.0.0.3", "[]"},
+		{SelectHostname, "web03.example.com", "[ACMweb03]"},
+		{SelectHostname, "WEB03", "[ACMweb03]"},
+		{SelectHostname, "acmdb01.example.com.", "[ACMdb01]"},
+		{SelectHostname, "web", "[]"},
+		{SelectProperty, "app=shop", "[ACMweb01 ACMweb02]"},
+		{SelectResourceID, "ID-4", "[ACMdb01]"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %s", tt.kind, tt.value), func(t *testing.T) {
+			s := newCatalogServer(t, resources, 2)
+			c := newTestClient(s.URL, 2, 2)
+
+			selector, err := ParseSelector(tt.kind, tt.value)
+			if err != nil {
+				t.Fatalf("ParseSelector: %v", err)
+			}
+			found, err := c.FindVirtualMachinesBy(context.Background(), []Selector{selector})
+			if err != nil {
+				t.Fatalf("FindVirtualMachinesBy: %v", err)
+			}
+			if got := fmt.Sprint(names(found[selector])); got != tt.want {
+				t.Errorf("machines = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindVirtualMachinesByResourceIDStopsEarly(t *testing.T) {
+	s := newCatalogServer(t, machines("ACMweb01", "ACMweb02", "ACMweb03", "ACMdb01"), 1)
+	c := newTestClient(s.URL, 1, 1)
+
+	selector, _ := ParseSelector(SelectResourceID, "id-1")
+	found, err := c.FindVirtualMachinesBy(context.Background(), []Selector{selector})
+	if err != nil {
+		t.Fatalf("FindVirtualMachinesBy: %v", err)
+	}
+	if got := fmt.Sprint(names(found[selector])); got != "[ACMweb01]" {
+		t.Errorf("machines = %s, want [ACMweb01]", got)
+	}
+	// The first page holds the only resource id, the scan stops there
+	if pages := s.pages(); len(pages) != 1 {
+		t.Errorf("requested pages %v, want [1]", pages)
+	}
+}
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		kind    SelectorKind
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{SelectResourceID, " 4b0a ", "resource-id 4b0a", false},
+		{SelectIP, "10.0.0.1", "ip 10.0.0.1", false},
+		{SelectIP, "::ffff:10.0.0.1", "ip 10.0.0.1", false},
+		{SelectIP, "10.0.0", "", true},
+		{SelectHostname, "web01.example.com", "hostname web01.example.com", false},
+		{SelectProperty, "app = shop=1", "property app= shop=1", false},
+		{SelectProperty, "app", "", true},
+		{SelectProperty, "=shop", "", true},
+		{SelectProperty, " ", "", true},
+		{SelectorKind("owner"), "me", "", true},
+	}
+	for _, tt := range tests {
+		selector, err := ParseSelector(tt.kind, tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSelector(%s, %q) error = %v, want error %v", tt.kind, tt.value, err, tt.wantErr)
+			continue
+		}
+		if err == nil && selector.String() != tt.want {
+			t.Errorf("ParseSelector(%s, %q) = %q, want %q", tt.kind, tt.value, selector, tt.want)
+		}
+	}
+}
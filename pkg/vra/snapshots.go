@@ -0,0 +1,78 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vra
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNoSnapshot is returned when a machine has no snapshot
+var ErrNoSnapshot = errors.New("vra: no snapshot")
+
+// Resource data keys of the snapshots of a machine
+const (
+	snapshotListKey         = "SNAPSHOT_LIST"
+	snapshotIDKey           = "SNAPSHOT_ID"
+	snapshotNameKey         = "SNAPSHOT_NAME"
+	snapshotDescriptionKey  = "SNAPSHOT_DESCRIPTION"
+	snapshotCreationDateKey = "SNAPSHOT_CREATION_DATE"
+)
+
+// Snapshot is a snapshot of a machine as listed in its resource data
+type Snapshot struct {
+	ID          string    `json:"id,omitempty"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Created     time.Time `json:"created"`
+}
+
+// Snapshots returns the snapshots of the machine, read from the resource data
+// of the catalog resource. A creation time that can not be parsed is zero.
+func (r *CatalogResource) Snapshots() []Snapshot {
+	var snapshots []Snapshot
+	for _, data := range r.ResourceData.Complex(snapshotListKey) {
+		snapshot := Snapshot{
+			ID:          data.String(snapshotIDKey),
+			Name:        data.String(snapshotNameKey),
+			Description: data.String(snapshotDescriptionKey),
+		}
+		if created, err := time.Parse(time.RFC3339, data.String(snapshotCreationDateKey)); err == nil {
+			snapshot.Created = created
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots
+}
+
+// NoSnapshotError is returned when a machine has no snapshot, it is an ErrNoSnapshot
+type NoSnapshotError struct {
+	Machine string
+}
+
+func (e *NoSnapshotError) Error() string {
+	return fmt.Sprintf("%s of virtual machine %q", ErrNoSnapshot, e.Machine)
+}
+
+func (e *NoSnapshotError) Unwrap() error {
+	return ErrNoSnapshot
+}
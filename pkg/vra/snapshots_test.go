@@ -0,0 +1,60 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vra
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSnapshots(t *testing.T) {
+	snapshot := func(name, created string) string {
+		return `{"type": "complex", "values": {"entries": [
+			{"key": "SNAPSHOT_NAME", "value": {"type": "string", "value": "` + name + `"}},
+			{"key": "SNAPSHOT_DESCRIPTION", "value": {"type": "string", "value": "before ` + name + `"}},
+			{"key": "SNAPSHOT_CREATION_DATE", "value": {"type": "string", "value": "` + created + `"}}
+		]}}`
+	}
+
+	tests := []struct {
+		name string
+		data LiteralMap
+		want []Snapshot
+	}{
+		{"none", literalMap(), nil},
+		{"complex", literalMap("SNAPSHOT_LIST", snapshot("s1", "2019-05-29T01:33:15Z")), []Snapshot{
+			{Name: "s1", Description: "before s1", Created: time.Date(2019, 5, 29, 1, 33, 15, 0, time.UTC)},
+		}},
+		{"multiple", literalMap("SNAPSHOT_LIST", `{"type": "multiple", "items": [`+snapshot("s1", "2019-05-29T01:33:15Z")+`, {"type": "string", "value": "skipped"}, `+snapshot("s2", "invalid")+`]}`), []Snapshot{
+			{Name: "s1", Description: "before s1", Created: time.Date(2019, 5, 29, 1, 33, 15, 0, time.UTC)},
+			{Name: "s2", Description: "before s2"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := CatalogResource{ResourceData: tt.data}
+			if got := resource.Snapshots(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Snapshots() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
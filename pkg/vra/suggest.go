@@ -0,0 +1,157 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vra
+
+import (
+	"context"
+	"sort"
+	"strings"
+)
+
+// SuggestVirtualMachineNames returns for every machine name the closest
+// virtual machine names in the catalog, at most max names per machine. The
+// names are returned without the tenant prefix, like they are used to look up
+// a machine. Names that differ too much are not suggested.
+func (c *Client) SuggestVirtualMachineNames(ctx context.Context, machines []string, prefix TenantPrefix, max int) (map[string][]string, error) {
+	candidates := newNameCandidates(prefix)
+	reset := func(string) {
+		candidates.reset()
+	}
+	err := c.scanVirtualMachines(ctx, c.machineTypeFilter(), reset, func(resources []CatalogResource) bool {
+		for _, resource := range resources {
+			candidates.add(resource)
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	return candidates.closest(machines, max), nil
+}
+
+// nameCandidates collects the names to suggest during a catalog scan
+type nameCandidates struct {
+	prefix TenantPrefix
+	names  []string
+	seen   map[string]bool
+}
+
+func newNameCandidates(prefix TenantPrefix) *nameCandidates {
+	n := &nameCandidates{prefix: prefix}
+	n.reset()
+	return n
+}
+
+// reset forgets the names collected so far
+func (n *nameCandidates) reset() {
+	n.names = nil
+	n.seen = make(map[string]bool)
+}
+
+// add collects the name of the resource
+func (n *nameCandidates) add(resource CatalogResource) {
+	// Use the shortest name without prefix, or the full name without a matching prefix
+	name := resource.Name
+	if remainders := n.prefix.remainders(resource.Name, false); len(remainders) > 0 {
+		name = remainders[len(remainders)-1]
+	}
+	if !n.seen[name] {
+		n.seen[name] = true
+		n.names = append(n.names, name)
+	}
+}
+
+// closest returns the closest collected names per machine
+func (n *nameCandidates) closest(machines []string, max int) map[string][]string {
+	suggestions := make(map[string][]string, len(machines))
+	for _, machine := range machines {
+		suggestions[machine] = closestNames(machine, n.names, max)
+	}
+	return suggestions
+}
+
+// closestNames returns at most max names ordered by edit distance to the
+// machine name, ignoring case. Only names within a third of the length of the
+// machine name (at least 2 edits) are returned.
+func closestNames(machine string, names []string, max int) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+
+	limit := len(machine) / 3
+	if limit < 2 {
+		limit = 2
+	}
+
+	var candidates []candidate
+	for _, name := range names {
+		distance := editDistance(strings.ToLower(machine), strings.ToLower(name))
+		if distance <= limit {
+			candidates = append(candidates, candidate{name: name, distance: distance})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].name < candidates[j].name
+	})
+
+	var closest []string
+	for i := 0; i < len(candidates) && i < max; i++ {
+		closest = append(closest, candidates[i].name)
+	}
+	return closest
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+func minInt(values ...int) int {
+	min := values[0]
+	for _, v := range values[1:] {
+		if v < min {
+			min = v
+		}
+	}
+	return min
+}
@@ -0,0 +1,82 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vra
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func TestClosestNames(t *testing.T) {
+	names := []string{"web01", "web02", "web10", "db01", "appserver01"}
+	tests := []struct {
+		machine string
+		max     int
+		want    string
+	}{
+		{"web03", 3, "[web01 web02 web10]"},
+		{"web03", 1, "[web01]"},
+		{"WEB01", 3, "[web01 web02 db01]"},
+		{"appserver1", 3, "[appserver01]"},
+		{"mail01", 3, "[]"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(closestNames(tt.machine, names, tt.max)); got != tt.want {
+			t.Errorf("closestNames(%q, %d) = %s, want %s", tt.machine, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"web01", "", 5},
+		{"", "web01", 5},
+		{"web01", "web01", 0},
+		{"web01", "web02", 1},
+		{"web01", "web10", 2},
+		{"web01", "wb01", 1},
+		{"kitten", "sitting", 3},
+		{"café", "cafe", 1},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSuggestVirtualMachineNames(t *testing.T) {
+	s := newCatalogServer(t, machines("ACMweb01", "ACMweb02", "ACMdb01"), 2)
+	c := newTestClient(s.URL, 2, 2)
+
+	suggestions, err := c.SuggestVirtualMachineNames(context.Background(), []string{"web1"}, TenantPrefix{}, 3)
+	if err != nil {
+		t.Fatalf("SuggestVirtualMachineNames: %v", err)
+	}
+	if got := fmt.Sprint(suggestions["web1"]); got != "[web01 web02]" {
+		t.Errorf("suggestions = %s, want [web01 web02]", got)
+	}
+}
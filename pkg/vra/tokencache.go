@@ -0,0 +1,157 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vra
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// TokenExpiryMargin is the time before expiration a cached token is no longer used
+const TokenExpiryMargin = 5 * time.Minute
+
+// TokenCache stores bearer tokens in a file that is only readable by the
+// current user. The tokens are stored by key, see TokenCacheKey.
+type TokenCache struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+// NewTokenCache returns a token cache stored in the file path
+func NewTokenCache(path string) *TokenCache {
+	return &TokenCache{Path: path}
+}
+
+// DefaultTokenCachePath returns the path of the token cache in the user cache directory
+func DefaultTokenCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "makeSnapshot", "tokens.json"), nil
+}
+
+// TokenCacheKey returns the cache key of the token for the baseURL, tenant and user
+func TokenCacheKey(baseURL, tenant, username string) string {
+	sum := sha256.Sum256([]byte(baseURL + "\n" + tenant + "\n" + username))
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns the cached token for the key
+func (tc *TokenCache) Get(key string) (*GetBearerTokenResponse, bool) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	tokens, err := tc.read()
+	if err != nil {
+		return nil, false
+	}
+	token, ok := tokens[key]
+	if !ok || token.ID == "" {
+		return nil, false
+	}
+	return &token, true
+}
+
+// Put stores the token for the key, expired tokens are removed from the cache
+func (tc *TokenCache) Put(key string, token *GetBearerTokenResponse) error {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	tokens, err := tc.read()
+	if err != nil {
+		tokens = make(map[string]GetBearerTokenResponse)
+	}
+	for k, t := range tokens {
+		if expires, err := t.ExpiresAt(); err != nil || time.Now().After(expires) {
+			delete(tokens, k)
+		}
+	}
+	tokens[key] = *token
+	return tc.write(tokens)
+}
+
+// Delete removes the token for the key from the cache
+func (tc *TokenCache) Delete(key string) error {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	tokens, err := tc.read()
+	if err != nil {
+		return nil
+	}
+	if _, ok := tokens[key]; !ok {
+		return nil
+	}
+	delete(tokens, key)
+	return tc.write(tokens)
+}
+
+func (tc *TokenCache) read() (map[string]GetBearerTokenResponse, error) {
+	data, err := ioutil.ReadFile(tc.Path)
+	if err != nil {
+		return nil, err
+	}
+	var tokens map[string]GetBearerTokenResponse
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// write replaces the cache file, the temporary file makes sure a concurrent
+// reader never sees a partially written cache.
+func (tc *TokenCache) write(tokens map[string]GetBearerTokenResponse) error {
+	data, err := json.Marshal(tokens)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(tc.Path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	file, err := ioutil.TempFile(dir, ".tokens-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := file.Chmod(0600); err != nil {
+		file.Close()
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), tc.Path)
+}
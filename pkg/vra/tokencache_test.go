@@ -0,0 +1,172 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vra
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// identityServer is a fake identity service that accepts the tokens in valid
+// and hands out "new-token" on a login
+type identityServer struct {
+	*httptest.Server
+
+	valid    map[string]bool
+	requests []string // method and path of every request
+	mu       sync.Mutex
+}
+
+func newIdentityServer(t *testing.T, valid ...string) *identityServer {
+	t.Helper()
+	s := &identityServer{valid: make(map[string]bool)}
+	for _, id := range valid {
+		s.valid[id] = true
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *identityServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/identity/api/tokens":
+		s.valid["new-token"] = true
+		json.NewEncoder(w).Encode(GetBearerTokenResponse{ID: "new-token", Expires: expiresIn(time.Hour), Tenant: "tenant"})
+	case r.Method == http.MethodHead && strings.HasPrefix(r.URL.Path, "/identity/api/tokens/"):
+		if s.valid[strings.TrimPrefix(r.URL.Path, "/identity/api/tokens/")] {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// logins returns the number of token requests
+func (s *identityServer) logins() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, request := range s.requests {
+		if strings.HasPrefix(request, http.MethodPost) {
+			n++
+		}
+	}
+	return n
+}
+
+func expiresIn(d time.Duration) string {
+	return time.Now().Add(d).UTC().Format(time.RFC3339)
+}
+
+func TestGetCachedBearerToken(t *testing.T) {
+	tests := []struct {
+		name       string
+		cached     *GetBearerTokenResponse
+		valid      []string
+		wantToken  string
+		wantCached bool
+		wantLogins int
+	}{
+		{"empty cache", nil, nil, "new-token", false, 1},
+		{"cache hit", &GetBearerTokenResponse{ID: "cached-token", Expires: expiresIn(time.Hour)}, []string{"cached-token"}, "cached-token", true, 0},
+		{"expires within the margin", &GetBearerTokenResponse{ID: "cached-token", Expires: expiresIn(TokenExpiryMargin / 2)}, []string{"cached-token"}, "new-token", false, 1},
+		{"expired", &GetBearerTokenResponse{ID: "cached-token", Expires: expiresIn(-time.Hour)}, []string{"cached-token"}, "new-token", false, 1},
+		{"revoked", &GetBearerTokenResponse{ID: "cached-token", Expires: expiresIn(time.Hour)}, nil, "new-token", false, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newIdentityServer(t, tt.valid...)
+			c := newTestClient(s.URL, 10, 1)
+			cache := NewTokenCache(filepath.Join(t.TempDir(), "tokens.json"))
+			key := TokenCacheKey(c.BaseURL, c.Tenant, "user@domain")
+			if tt.cached != nil {
+				if err := cache.Put(key, tt.cached); err != nil {
+					t.Fatalf("Put: %v", err)
+				}
+			}
+
+			token, cached, err := c.GetCachedBearerToken(context.Background(), cache, "user@domain", "secret")
+			if err != nil {
+				t.Fatalf("GetCachedBearerToken: %v", err)
+			}
+			if token.ID != tt.wantToken || cached != tt.wantCached || c.bearerToken != tt.wantToken {
+				t.Errorf("token %q, cached %v, client token %q, want %q, %v", token.ID, cached, c.bearerToken, tt.wantToken, tt.wantCached)
+			}
+			if logins := s.logins(); logins != tt.wantLogins {
+				t.Errorf("%d logins, want %d", logins, tt.wantLogins)
+			}
+			if tt.wantCached && (len(s.requests) != 1 || !strings.HasPrefix(s.requests[0], http.MethodHead)) {
+				t.Errorf("requests %v, want a single validation", s.requests)
+			}
+			if stored, ok := cache.Get(key); !ok || stored.ID != tt.wantToken {
+				t.Errorf("cache holds %v, want %q", stored, tt.wantToken)
+			}
+		})
+	}
+}
+
+func TestTokenCachePut(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache", "tokens.json")
+	cache := NewTokenCache(path)
+
+	if err := cache.Put("expired", &GetBearerTokenResponse{ID: "old-token", Expires: expiresIn(-time.Minute)}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := cache.Put("valid", &GetBearerTokenResponse{ID: "new-token", Expires: expiresIn(time.Hour)}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("cache file mode %v, want 0600", mode)
+	}
+	if _, ok := cache.Get("expired"); ok {
+		t.Errorf("expired token is still cached")
+	}
+	if token, ok := cache.Get("valid"); !ok || token.ID != "new-token" {
+		t.Errorf("Get(valid) = %v, %v, want new-token", token, ok)
+	}
+
+	if err := cache.Delete("valid"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok := cache.Get("valid"); ok {
+		t.Errorf("deleted token is still cached")
+	}
+}
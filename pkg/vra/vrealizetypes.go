@@ -0,0 +1,364 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vra
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// GetBearerTokenRequest ...
+type GetBearerTokenRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Tenant   string `json:"tenant"`
+}
+
+// GetBearerTokenResponse ...
+type GetBearerTokenResponse struct {
+	Expires string `json:"expires"`
+	ID      string `json:"id"`
+	Tenant  string `json:"tenant"`
+}
+
+// ExpiresAt returns the expiration time of the token
+func (r *GetBearerTokenResponse) ExpiresAt() (time.Time, error) {
+	return time.Parse(time.RFC3339, r.Expires)
+}
+
+// SnapShotTemplate ...
+type SnapShotTemplate struct {
+	Type        string      `json:"type"`
+	ResourceID  string      `json:"resourceId"`
+	ActionID    string      `json:"actionId"`
+	Description interface{} `json:"description"`
+	Data        Data        `json:"data"`
+}
+
+// ResourceActionTemplate is the request template of any resource action,
+// the data is sent back unchanged unless a field is set
+type ResourceActionTemplate struct {
+	Type        string                     `json:"type"`
+	ResourceID  string                     `json:"resourceId"`
+	ActionID    string                     `json:"actionId"`
+	Description interface{}                `json:"description"`
+	Data        map[string]json.RawMessage `json:"data"`
+}
+
+// SetData sets a data field of the template
+func (t *ResourceActionTemplate) SetData(key string, value interface{}) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if t.Data == nil {
+		t.Data = make(map[string]json.RawMessage)
+	}
+	t.Data[key] = b
+	return nil
+}
+
+// Data ...
+type Data struct {
+	ProviderASDPRESENTATIONINSTANCE interface{} `json:"provider-__ASD_PRESENTATION_INSTANCE"`
+	ProviderAsdTenantRef            string      `json:"provider-__asd_tenantRef"`
+	ProviderDeleteExisting          interface{} `json:"provider-deleteExisting"`
+	ProviderDescription             interface{} `json:"provider-description"`
+	ProviderExistingSnapshotName    interface{} `json:"provider-existingSnapshotName"`
+	ProviderName                    interface{} `json:"provider-name"`
+
+	// Extra holds the template fields that are not known by this struct,
+	// they are sent back unchanged with the request.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// dataFields is Data without its JSON methods
+type dataFields Data
+
+// UnmarshalJSON decodes the known fields and keeps all others in Extra
+func (d *Data) UnmarshalJSON(b []byte) error {
+	if err := json.Unmarshal(b, (*dataFields)(d)); err != nil {
+		return err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(b, &all); err != nil {
+		return err
+	}
+	known, err := json.Marshal(dataFields{})
+	if err != nil {
+		return err
+	}
+	var knownFields map[string]json.RawMessage
+	if err := json.Unmarshal(known, &knownFields); err != nil {
+		return err
+	}
+	for key := range knownFields {
+		delete(all, key)
+	}
+
+	d.Extra = all
+	return nil
+}
+
+// MarshalJSON encodes the known fields together with the fields in Extra
+func (d Data) MarshalJSON() ([]byte, error) {
+	b, err := json.Marshal(dataFields(d))
+	if err != nil || len(d.Extra) == 0 {
+		return b, err
+	}
+
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil, err
+	}
+	for key, value := range d.Extra {
+		if _, ok := all[key]; !ok {
+			all[key] = value
+		}
+	}
+	return json.Marshal(all)
+}
+
+// Fields returns the names of all data fields of the template, sorted
+func (d *Data) Fields() []string {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(b, &all); err != nil {
+		return nil
+	}
+
+	fields := make([]string, 0, len(all))
+	for key := range all {
+		fields = append(fields, key)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// SetExtra sets a data field that is not known by this struct, like an
+// option of the tenant's snapshot action. A field that is not in the
+// template results in an *UnsupportedFieldError.
+func (d *Data) SetExtra(key string, value interface{}) error {
+	if _, ok := d.Extra[key]; !ok {
+		return &UnsupportedFieldError{Field: key, Fields: d.Fields()}
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	d.Extra[key] = b
+	return nil
+}
+
+// ErrorResponse is the error envelope returned by the vRA APIs
+type ErrorResponse struct {
+	Errors []ErrorDetail `json:"errors"`
+}
+
+// ErrorDetail ...
+type ErrorDetail struct {
+	Code          int    `json:"code"`
+	Source        string `json:"source"`
+	Message       string `json:"message"`
+	SystemMessage string `json:"systemMessage"`
+	MoreInfoURL   string `json:"moreInfoUrl"`
+}
+
+// Link ...
+type Link struct {
+	Type string `json:"@type"`
+	Rel  string `json:"rel"`
+	Href string `json:"href"`
+}
+
+// PageMetadata describes the paging of a list response
+type PageMetadata struct {
+	Size          int `json:"size"`
+	TotalElements int `json:"totalElements"`
+	TotalPages    int `json:"totalPages"`
+	Number        int `json:"number"`
+	Offset        int `json:"offset"`
+}
+
+// Ref is a reference to another vRA object
+type Ref struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+// CatalogResourcePage is a single page of consumer catalog resources
+type CatalogResourcePage struct {
+	Links    []Link            `json:"links"`
+	Content  []CatalogResource `json:"content"`
+	Metadata PageMetadata      `json:"metadata"`
+}
+
+// CatalogResource ...
+type CatalogResource struct {
+	Type            string          `json:"@type"`
+	ID              string          `json:"id"`
+	IconID          string          `json:"iconId"`
+	ResourceTypeRef Ref             `json:"resourceTypeRef"`
+	Name            string          `json:"name"`
+	Description     string          `json:"description"`
+	Status          string          `json:"status"`
+	CatalogItem     Ref             `json:"catalogItem"`
+	RequestID       string          `json:"requestId"`
+	ProviderBinding ProviderBinding `json:"providerBinding"`
+	DateCreated     string          `json:"dateCreated"`
+	LastUpdated     string          `json:"lastUpdated"`
+	HasChildren     bool            `json:"hasChildren"`
+	ResourceData    LiteralMap      `json:"resourceData"`
+}
+
+// ProviderBinding ...
+type ProviderBinding struct {
+	BindingID   string `json:"bindingId"`
+	ProviderRef Ref    `json:"providerRef"`
+}
+
+// LiteralMap is the vRA representation of a key/value map
+type LiteralMap struct {
+	Entries []LiteralMapEntry `json:"entries"`
+}
+
+// LiteralMapEntry ...
+type LiteralMapEntry struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// literalValue is a single value of a LiteralMap, a simple value like a
+// string or integer, a list ("multiple") or a map ("complex")
+type literalValue struct {
+	Type   string            `json:"type"`
+	Value  json.RawMessage   `json:"value"`
+	Items  []json.RawMessage `json:"items"`
+	Values LiteralMap        `json:"values"`
+}
+
+// get returns the decoded value of the key
+func (m LiteralMap) get(key string) (literalValue, bool) {
+	var value literalValue
+	for _, entry := range m.Entries {
+		if entry.Key == key {
+			if err := json.Unmarshal(entry.Value, &value); err != nil {
+				return value, false
+			}
+			return value, true
+		}
+	}
+	return value, false
+}
+
+// String returns a simple value of the key as text, empty when the key is
+// missing or the value is not a simple value
+func (m LiteralMap) String(key string) string {
+	value, ok := m.get(key)
+	if !ok || len(value.Value) == 0 {
+		return ""
+	}
+
+	var text string
+	if err := json.Unmarshal(value.Value, &text); err == nil {
+		return text
+	}
+	// Numbers and booleans as written by vRA, not null or nested values
+	switch value.Value[0] {
+	case 'n', '{', '[':
+		return ""
+	}
+	return string(value.Value)
+}
+
+// Complex returns the maps of a "complex" value or of the "complex" items of
+// a "multiple" value of the key
+func (m LiteralMap) Complex(key string) []LiteralMap {
+	value, ok := m.get(key)
+	if !ok {
+		return nil
+	}
+	if value.Type == "complex" {
+		return []LiteralMap{value.Values}
+	}
+
+	var maps []LiteralMap
+	for _, raw := range value.Items {
+		var item literalValue
+		if err := json.Unmarshal(raw, &item); err == nil && item.Type == "complex" {
+			maps = append(maps, item.Values)
+		}
+	}
+	return maps
+}
+
+// ResourceActionPage is a single page of resource actions
+type ResourceActionPage struct {
+	Links    []Link           `json:"links"`
+	Content  []ResourceAction `json:"content"`
+	Metadata PageMetadata     `json:"metadata"`
+}
+
+// ResourceAction is a day-2 operation that can be requested on a resource
+type ResourceAction struct {
+	Type           string `json:"@type"`
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	Description    string `json:"description"`
+	IconID         string `json:"iconId"`
+	OperationType  string `json:"type"`
+	ExtensionID    string `json:"extensionId"`
+	ProviderTypeID string `json:"providerTypeId"`
+	BindingID      string `json:"bindingId"`
+	HasForm        bool   `json:"hasForm"`
+}
+
+// ResourceActionRequest is the status of a submitted resource action
+type ResourceActionRequest struct {
+	Type              string            `json:"@type"`
+	ID                string            `json:"id"`
+	RequestNumber     int               `json:"requestNumber"`
+	State             string            `json:"state"`
+	StateName         string            `json:"stateName"`
+	Phase             string            `json:"phase"`
+	ApprovalStatus    string            `json:"approvalStatus"`
+	ExecutionStatus   string            `json:"executionStatus"`
+	WaitingStatus     string            `json:"waitingStatus"`
+	Description       string            `json:"description"`
+	Reasons           string            `json:"reasons"`
+	DateCreated       string            `json:"dateCreated"`
+	DateSubmitted     string            `json:"dateSubmitted"`
+	DateCompleted     string            `json:"dateCompleted"`
+	RequestCompletion RequestCompletion `json:"requestCompletion"`
+	ResourceRef       Ref               `json:"resourceRef"`
+	ResourceActionRef Ref               `json:"resourceActionRef"`
+}
+
+// RequestCompletion ...
+type RequestCompletion struct {
+	RequestCompletionState string `json:"requestCompletionState"`
+	CompletionDetails      string `json:"completionDetails"`
+}
@@ -0,0 +1,162 @@
+// Copyright © 2019 Albert W. Alberts <a.w.alberts@tisgoud.nl>
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package vra
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestDataRoundTrip(t *testing.T) {
+	template := `{
+		"provider-__ASD_PRESENTATION_INSTANCE": null,
+		"provider-__asd_tenantRef": "tenant",
+		"provider-deleteExisting": true,
+		"provider-description": null,
+		"provider-existingSnapshotName": null,
+		"provider-name": null,
+		"provider-includeMemory": false,
+		"provider-quiesce": {"nested": [1, 2]}
+	}`
+
+	var data Data
+	if err := json.Unmarshal([]byte(template), &data); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if data.ProviderAsdTenantRef != "tenant" {
+		t.Errorf("ProviderAsdTenantRef = %q, want tenant", data.ProviderAsdTenantRef)
+	}
+	if len(data.Extra) != 2 {
+		t.Errorf("Extra = %v, want the 2 unknown fields", data.Extra)
+	}
+
+	// The unknown fields are sent back unchanged
+	b, err := json.Marshal(data)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var got, want map[string]interface{}
+	json.Unmarshal(b, &got)
+	json.Unmarshal([]byte(template), &want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip = %s, want %s", b, template)
+	}
+}
+
+// literalMap builds a LiteralMap from keys and JSON values
+func literalMap(entries ...string) LiteralMap {
+	var m LiteralMap
+	for i := 0; i+1 < len(entries); i += 2 {
+		m.Entries = append(m.Entries, LiteralMapEntry{Key: entries[i], Value: json.RawMessage(entries[i+1])})
+	}
+	return m
+}
+
+func TestLiteralMapString(t *testing.T) {
+	m := literalMap(
+		"string", `{"type": "string", "value": "web01"}`,
+		"integer", `{"type": "integer", "value": 4}`,
+		"boolean", `{"type": "boolean", "value": true}`,
+		"null", `{"type": "string", "value": null}`,
+		"complex", `{"type": "complex", "values": {"entries": []}}`,
+		"invalid", `"not a literal value"`,
+	)
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"string", "web01"},
+		{"integer", "4"},
+		{"boolean", "true"},
+		{"null", ""},
+		{"complex", ""},
+		{"invalid", ""},
+		{"missing", ""},
+	}
+	for _, tt := range tests {
+		if got := m.String(tt.key); got != tt.want {
+			t.Errorf("String(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestLiteralMapComplex(t *testing.T) {
+	entry := `{"type": "complex", "values": {"entries": [{"key": "NETWORK_ADDRESS", "value": {"type": "string", "value": "10.0.0.1"}}]}}`
+	m := literalMap(
+		"complex", entry,
+		"multiple", `{"type": "multiple", "items": [`+entry+`, {"type": "string", "value": "skipped"}, `+entry+`]}`,
+		"string", `{"type": "string", "value": "web01"}`,
+	)
+
+	tests := []struct {
+		key  string
+		want int
+	}{
+		{"complex", 1},
+		{"multiple", 2},
+		{"string", 0},
+		{"missing", 0},
+	}
+	for _, tt := range tests {
+		maps := m.Complex(tt.key)
+		if len(maps) != tt.want {
+			t.Errorf("Complex(%q) = %d maps, want %d", tt.key, len(maps), tt.want)
+			continue
+		}
+		for _, value := range maps {
+			if got := value.String("NETWORK_ADDRESS"); got != "10.0.0.1" {
+				t.Errorf("Complex(%q) NETWORK_ADDRESS = %q, want 10.0.0.1", tt.key, got)
+			}
+		}
+	}
+}
+
+func TestDataSetExtra(t *testing.T) {
+	var data Data
+	if err := json.Unmarshal([]byte(`{"provider-name": null, "provider-includeMemory": false}`), &data); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	wantFields := "[provider-__ASD_PRESENTATION_INSTANCE provider-__asd_tenantRef provider-deleteExisting provider-description provider-existingSnapshotName provider-includeMemory provider-name]"
+	if fields := fmt.Sprint(data.Fields()); fields != wantFields {
+		t.Errorf("Fields() = %s, want %s", fields, wantFields)
+	}
+
+	if err := data.SetExtra("provider-includeMemory", true); err != nil {
+		t.Errorf("SetExtra(provider-includeMemory): %v", err)
+	}
+	if got := string(data.Extra["provider-includeMemory"]); got != "true" {
+		t.Errorf("provider-includeMemory = %s, want true", got)
+	}
+
+	err := data.SetExtra("provider-quiesce", true)
+	var unsupported *UnsupportedFieldError
+	if !errors.As(err, &unsupported) || !errors.Is(err, ErrUnsupportedField) {
+		t.Fatalf("SetExtra(provider-quiesce) = %v, want an *UnsupportedFieldError", err)
+	}
+	if unsupported.Field != "provider-quiesce" || fmt.Sprint(unsupported.Fields) != wantFields {
+		t.Errorf("UnsupportedFieldError = %+v", unsupported)
+	}
+}